@@ -0,0 +1,135 @@
+package admin
+
+import (
+	"encoding/json"
+	"expvar"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/luweimy/goutil/workerq"
+)
+
+// Status is snapshot of WorkerQueue internals.
+type Status struct {
+	Concurrency int          `json:"concurrency"`
+	Working     int          `json:"working"`
+	Backlog     int          `json:"backlog"`
+	Paused      bool         `json:"paused"`
+	Workers     []WorkerInfo `json:"workers"`
+	Failures    []Failure    `json:"failures"`
+}
+
+type WorkerInfo struct {
+	ID            uint64    `json:"id"`
+	Name          string    `json:"name,omitempty"`
+	State         string    `json:"state"`
+	Age           float64   `json:"age_seconds"` // since enqueued if queued, since started if running
+	LastHeartbeat time.Time `json:"last_heartbeat"`
+	Progress      float64   `json:"progress"`
+	Message       string    `json:"message,omitempty"`
+}
+
+type Failure struct {
+	WorkerID uint64    `json:"worker_id"`
+	Name     string    `json:"name,omitempty"`
+	Error    string    `json:"error"`
+	Time     time.Time `json:"time"`
+	Run      float64   `json:"run_seconds"`
+}
+
+// GetStatus return snapshot of q, it does not block while SetConcurrency waiting for processing workers.
+func GetStatus(q *workerq.WorkerQueue) Status {
+	now := time.Now()
+	s := Status{
+		Concurrency: q.Concurrency(),
+		Working:     q.NumWorkingWorkers(),
+		Backlog:     q.NumBacklogWorkers(),
+		Paused:      q.Paused(),
+		Workers:     []WorkerInfo{},
+		Failures:    []Failure{},
+	}
+	for _, w := range q.Workers() {
+		since := w.EnqueuedAt
+		if w.State == workerq.WorkerRunning {
+			since = w.StartedAt
+		}
+		s.Workers = append(s.Workers, WorkerInfo{
+			ID:            w.ID,
+			Name:          w.Name,
+			State:         string(w.State),
+			Age:           now.Sub(since).Seconds(),
+			LastHeartbeat: w.LastHeartbeat,
+			Progress:      w.Progress.Fraction,
+			Message:       w.Progress.Message,
+		})
+	}
+	for _, f := range q.RecentFailures() {
+		s.Failures = append(s.Failures, Failure{
+			WorkerID: f.WorkerID,
+			Name:     f.Name,
+			Error:    f.Err.Error(),
+			Time:     f.Time,
+			Run:      f.Run.Seconds(),
+		})
+	}
+	return s
+}
+
+// Publish publish status of q through expvar with name, it panics if name is published already.
+func Publish(name string, q *workerq.WorkerQueue) {
+	expvar.Publish(name, expvar.Func(func() interface{} {
+		return GetStatus(q)
+	}))
+}
+
+// NewHandler create opt-in admin handler of q, mount it with http.StripPrefix if needed.
+//
+//	GET  /            status as JSON
+//	POST /concurrency set concurrency by form value n, it applies after processing workers done
+//	POST /pause       pause dispatching backlog workers
+//	POST /resume      resume dispatching backlog workers
+//	POST /cancel      cancel worker by form value id
+func NewHandler(q *workerq.WorkerQueue) http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /{$}", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, GetStatus(q))
+	})
+	mux.HandleFunc("POST /concurrency", func(w http.ResponseWriter, r *http.Request) {
+		n, err := strconv.Atoi(r.FormValue("n"))
+		if err != nil || n <= 0 {
+			http.Error(w, "invalid concurrency", http.StatusBadRequest)
+			return
+		}
+		// SetConcurrency blocks until processing workers done, do not block the request.
+		go q.SetConcurrency(n)
+		writeJSON(w, http.StatusAccepted, map[string]int{"concurrency": n})
+	})
+	mux.HandleFunc("POST /pause", func(w http.ResponseWriter, r *http.Request) {
+		q.Pause()
+		writeJSON(w, http.StatusOK, map[string]bool{"paused": true})
+	})
+	mux.HandleFunc("POST /resume", func(w http.ResponseWriter, r *http.Request) {
+		q.Resume()
+		writeJSON(w, http.StatusOK, map[string]bool{"paused": false})
+	})
+	mux.HandleFunc("POST /cancel", func(w http.ResponseWriter, r *http.Request) {
+		id, err := strconv.ParseUint(r.FormValue("id"), 10, 64)
+		if err != nil {
+			http.Error(w, "invalid worker id", http.StatusBadRequest)
+			return
+		}
+		if !q.CancelWorker(id) {
+			http.Error(w, "worker not found", http.StatusNotFound)
+			return
+		}
+		writeJSON(w, http.StatusOK, map[string]uint64{"canceled": id})
+	})
+	return mux
+}
+
+func writeJSON(w http.ResponseWriter, code int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	json.NewEncoder(w).Encode(v)
+}
@@ -0,0 +1,143 @@
+package admin
+
+import (
+	"encoding/json"
+	"errors"
+	"expvar"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/luweimy/goutil/workerq"
+)
+
+func TestHandler(t *testing.T) {
+	wq := workerq.New(1).Start()
+	defer wq.Stop()
+
+	errFailed := errors.New("failed")
+	wq.AddWorkerFunc(nil, func(worker *workerq.Worker) error {
+		return errFailed
+	}).Wait()
+
+	running := workerq.NewWorker(nil, func(worker *workerq.Worker) error {
+		<-worker.Done()
+		return nil
+	}).SetName("running")
+	wq.AddWorker(running)
+
+	srv := httptest.NewServer(NewHandler(wq))
+	defer srv.Close()
+
+	post := func(path string, form url.Values) int {
+		resp, err := http.PostForm(srv.URL+path, form)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		return resp.StatusCode
+	}
+	status := func() Status {
+		resp, err := http.Get(srv.URL + "/")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+		var s Status
+		if err := json.NewDecoder(resp.Body).Decode(&s); err != nil {
+			t.Fatal(err)
+		}
+		return s
+	}
+
+	if code := post("/pause", nil); code != http.StatusOK {
+		t.Errorf("except 200, actual %v", code)
+	}
+	queued := wq.AddWorkerFunc(nil, func(worker *workerq.Worker) error {
+		return nil
+	})
+
+	var s Status
+	for i := 0; i < 100; i++ {
+		if s = status(); len(s.Workers) == 2 {
+			break
+		}
+	}
+	if !s.Paused || s.Concurrency != 1 || len(s.Failures) != 1 || s.Failures[0].Error != "failed" {
+		t.Errorf("status not except, %+v", s)
+	}
+	if len(s.Workers) != 2 || s.Workers[0].Name != "running" || s.Workers[0].State != "running" || s.Workers[1].State != "queued" {
+		t.Errorf("workers not except, %+v", s.Workers)
+	}
+
+	if code := post("/cancel", url.Values{"id": {"0"}}); code != http.StatusNotFound {
+		t.Errorf("except 404, actual %v", code)
+	}
+	if code := post("/cancel", url.Values{"id": {strconv.FormatUint(running.ID(), 10)}}); code != http.StatusOK {
+		t.Errorf("except 200, actual %v", code)
+	}
+	if code := post("/resume", nil); code != http.StatusOK {
+		t.Errorf("except 200, actual %v", code)
+	}
+	running.Wait()
+	queued.Wait()
+
+	if code := post("/concurrency", url.Values{"n": {"2"}}); code != http.StatusAccepted {
+		t.Errorf("except 202, actual %v", code)
+	}
+}
+
+func TestPublish(t *testing.T) {
+	wq := workerq.New(3)
+	name := fmt.Sprintf("workerq_admin_test_%p", wq) // unique in case of -count
+	Publish(name, wq)
+	var s Status
+	if err := json.Unmarshal([]byte(expvar.Get(name).String()), &s); err != nil {
+		t.Fatal(err)
+	}
+	if s.Concurrency != 3 {
+		t.Errorf("except 3, actual %v", s.Concurrency)
+	}
+}
+
+func TestHandler_Resizing(t *testing.T) {
+	wq := workerq.New(1).Start()
+	defer wq.Stop()
+	release := make(chan struct{})
+	defer close(release)
+	started := make(chan struct{})
+	wq.AddWorkerFunc(nil, func(worker *workerq.Worker) error {
+		close(started)
+		<-release
+		return nil
+	})
+	<-started
+
+	srv := httptest.NewServer(NewHandler(wq))
+	defer srv.Close()
+	resp, err := http.PostForm(srv.URL+"/concurrency", url.Values{"n": {"2"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	time.Sleep(time.Millisecond * 20) // SetConcurrency waits for the running worker
+
+	// status is served while SetConcurrency waiting.
+	client := &http.Client{Timeout: time.Second}
+	resp, err = client.Get(srv.URL + "/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	var s Status
+	if err := json.NewDecoder(resp.Body).Decode(&s); err != nil {
+		t.Fatal(err)
+	}
+	if s.Concurrency != 1 || s.Working != 1 {
+		t.Errorf("except concurrency 1 working 1, actual %d %d", s.Concurrency, s.Working)
+	}
+}
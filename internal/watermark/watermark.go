@@ -0,0 +1,140 @@
+// Package watermark track length of queues against high and low watermarks, shared by syncq, syncq2 and workerq.
+package watermark
+
+import (
+	"context"
+	"sync"
+)
+
+// Watermark notify when length rises to high watermark, and when it falls back to low watermark afterwards.
+// the zero value is disabled until Set called.
+type Watermark struct {
+	mu    sync.Mutex
+	high  int
+	low   int
+	above bool // length rose to high and not fell to low yet
+	fn    func(high bool, n int)
+	subs  map[chan bool]bool // value is true if subscriber receives inverted state
+	below chan struct{}      // closed when length falls to low, nil if not waited
+}
+
+// Set set watermarks and callback fn(may be nil), high <= 0 disable notifications.
+// low is limited below high. fn is called synchronously while queue updating, it must be cheap
+// and must not call the queue.
+func (w *Watermark) Set(high, low int, fn func(high bool, n int)) {
+	if low >= high {
+		low = high - 1
+	}
+	if low < 0 {
+		low = 0
+	}
+	withLock(&w.mu, func() {
+		w.high, w.low, w.fn = high, low, fn
+		w.above = false
+		if w.below != nil {
+			close(w.below)
+			w.below = nil
+		}
+	})
+}
+
+// Update check length n against watermarks, queues call it in order of length changes.
+func (w *Watermark) Update(n int) {
+	withLock(&w.mu, func() {
+		if w.high <= 0 {
+			return
+		}
+		switch {
+		case !w.above && n >= w.high:
+			w.above = true
+		case w.above && n <= w.low:
+			w.above = false
+			if w.below != nil {
+				close(w.below)
+				w.below = nil
+			}
+		default:
+			return
+		}
+		if w.fn != nil {
+			w.fn(w.above, n)
+		}
+		for ch, invert := range w.subs {
+			// keep the latest state only if subscriber is slow.
+			select {
+			case <-ch:
+			default:
+			}
+			ch <- w.above != invert
+		}
+	})
+}
+
+// WaitBelow block until length is below high watermark, or falls to low watermark, or ctx done.
+// falling is not missed even though length rises again soon.
+func (w *Watermark) WaitBelow(ctx context.Context) error {
+	var below chan struct{}
+	withLock(&w.mu, func() {
+		if !w.above {
+			return
+		}
+		if w.below == nil {
+			w.below = make(chan struct{})
+		}
+		below = w.below
+	})
+	if below == nil {
+		return nil
+	}
+	select {
+	case <-below:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Above report whether length rose to high watermark and not fell to low yet.
+func (w *Watermark) Above() bool {
+	var above bool
+	withLock(&w.mu, func() {
+		above = w.above
+	})
+	return above
+}
+
+// Subscribe return channel receives true when length rose to high watermark, false when fell to low,
+// slow subscriber only receives the latest state. the channel is closed when unsubscribe func called.
+func (w *Watermark) Subscribe() (<-chan bool, func()) {
+	return w.subscribe(false)
+}
+
+// SubscribeBelow is like Subscribe, but the channel receives true when length fell to low, false when rose to high.
+func (w *Watermark) SubscribeBelow() (<-chan bool, func()) {
+	return w.subscribe(true)
+}
+
+func (w *Watermark) subscribe(invert bool) (<-chan bool, func()) {
+	ch := make(chan bool, 1)
+	withLock(&w.mu, func() {
+		if w.subs == nil {
+			w.subs = make(map[chan bool]bool)
+		}
+		w.subs[ch] = invert
+	})
+	var once sync.Once
+	return ch, func() {
+		once.Do(func() {
+			withLock(&w.mu, func() {
+				delete(w.subs, ch)
+				close(ch)
+			})
+		})
+	}
+}
+
+func withLock(lk sync.Locker, fn func()) {
+	lk.Lock()
+	defer lk.Unlock() // in case fn panics
+	fn()
+}
@@ -0,0 +1,76 @@
+package watermark
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestWatermark(t *testing.T) {
+	var (
+		w      Watermark
+		events []bool
+	)
+	w.Update(100) // disabled
+	w.Set(3, 1, func(high bool, n int) {
+		events = append(events, high)
+	})
+	ch, unsubscribe := w.Subscribe()
+
+	for _, n := range []int{1, 2, 3, 4, 3, 2, 3, 1, 0, 2, 3} {
+		w.Update(n)
+	}
+	if len(events) != 3 || !events[0] || events[1] || !events[2] {
+		t.Errorf("except [true false true], actual %v", events)
+	}
+	if !w.Above() {
+		t.Error("except above")
+	}
+	if v := <-ch; !v {
+		t.Error("except subscriber receives the latest state true")
+	}
+	unsubscribe()
+	unsubscribe()
+	if _, ok := <-ch; ok {
+		t.Error("except channel closed")
+	}
+}
+
+func TestWatermark_Set(t *testing.T) {
+	var w Watermark
+	w.Set(2, 5, nil)
+	if w.low != 1 {
+		t.Errorf("except low limited to 1, actual %d", w.low)
+	}
+}
+
+func TestWatermark_WaitBelow(t *testing.T) {
+	var w Watermark
+	w.Set(1, 0, nil)
+	if err := w.WaitBelow(context.Background()); err != nil {
+		t.Errorf("except nil, actual %v", err)
+	}
+
+	w.Update(1)
+	ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond*10)
+	defer cancel()
+	if err := w.WaitBelow(ctx); err != context.DeadlineExceeded {
+		t.Errorf("except %v, actual %v", context.DeadlineExceeded, err)
+	}
+
+	ch, unsubscribe := w.SubscribeBelow()
+	defer unsubscribe()
+	done := make(chan error)
+	go func() {
+		done <- w.WaitBelow(context.Background())
+	}()
+	time.Sleep(time.Millisecond * 10)
+	w.Update(0)
+	w.Update(1) // rises again soon, falling is not missed
+	if err := <-done; err != nil {
+		t.Errorf("except nil, actual %v", err)
+	}
+	if v := <-ch; v {
+		t.Error("except subscriber receives the latest inverted state false")
+	}
+}
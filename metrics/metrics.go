@@ -0,0 +1,266 @@
+package metrics
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/luweimy/goutil/observer"
+	"github.com/luweimy/goutil/workerq"
+)
+
+// DefaultBuckets is upper bounds(in seconds) of wait-time and run-time histograms.
+var DefaultBuckets = []float64{.005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10}
+
+// Queue is queue which length can be exported, e.g. syncq.SyncQueue and syncq2.SyncQueue.
+type Queue interface {
+	Len() int
+}
+
+// Registry collect metrics of queues and worker queues by name,
+// and serve them in Prometheus text exposition format.
+type Registry struct {
+	mu         sync.Mutex
+	collectors map[string]*collector
+}
+
+func NewRegistry() *Registry {
+	return &Registry{
+		collectors: make(map[string]*collector),
+	}
+}
+
+// Observer return observer collects events of the queue named name,
+// pass it to syncq.NewWithObserver, syncq2.NewWithObserver or workerq.NewWithObserver.
+func (r *Registry) Observer(name string) observer.Observer {
+	return r.collector(name)
+}
+
+// RegisterQueue export length of q as queue named name, it replaces queue or worker queue registered with name.
+func (r *Registry) RegisterQueue(name string, q Queue) {
+	c := r.collector(name)
+	withLock(&r.mu, func() {
+		c.queue, c.workerQueue = q, nil
+	})
+}
+
+// RegisterWorkerQueue export backlog length, worker counts and concurrency of q as queue named name,
+// it replaces queue or worker queue registered with name.
+func (r *Registry) RegisterWorkerQueue(name string, q *workerq.WorkerQueue) {
+	c := r.collector(name)
+	withLock(&r.mu, func() {
+		c.queue, c.workerQueue = nil, q
+	})
+}
+
+func (r *Registry) collector(name string) *collector {
+	var c *collector
+	withLock(&r.mu, func() {
+		c = r.collectors[name]
+		if c == nil {
+			c = newCollector(name)
+			r.collectors[name] = c
+		}
+	})
+	return c
+}
+
+// ServeHTTP serve metrics in Prometheus text exposition format.
+func (r *Registry) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	r.WriteTo(w)
+}
+
+// WriteTo write metrics in Prometheus text exposition format to w.
+// queues are read after mu released, so a slow queue does not block registration and observers.
+func (r *Registry) WriteTo(w io.Writer) (int64, error) {
+	var collectors []snapshot
+	withLock(&r.mu, func() {
+		collectors = make([]snapshot, 0, len(r.collectors))
+		for _, c := range r.collectors {
+			collectors = append(collectors, snapshot{collector: c, queue: c.queue, workerQueue: c.workerQueue})
+		}
+	})
+	sort.Slice(collectors, func(i, j int) bool {
+		return collectors[i].name < collectors[j].name
+	})
+	buf := &bytes.Buffer{}
+	for _, f := range families {
+		fmt.Fprintf(buf, "# HELP %s %s\n# TYPE %s %s\n", f.name, f.help, f.name, f.typ)
+		for i := range collectors {
+			f.write(buf, f.name, &collectors[i])
+		}
+	}
+	return buf.WriteTo(w)
+}
+
+// snapshot is collector with queues registered when snapshot taken, they shadow fields of collector guarded by mu.
+type snapshot struct {
+	*collector
+	queue       Queue
+	workerQueue *workerq.WorkerQueue
+}
+
+// collector collect events of a queue.
+type collector struct {
+	observer.Nop
+	name        string
+	queue       Queue
+	workerQueue *workerq.WorkerQueue
+
+	enqueued, dequeued, dropped                int64
+	started, finished, failed, panics, retries int64
+	queueWait, workerWait, workerRun           *histogram
+}
+
+func newCollector(name string) *collector {
+	return &collector{
+		name:       name,
+		queueWait:  newHistogram(DefaultBuckets),
+		workerWait: newHistogram(DefaultBuckets),
+		workerRun:  newHistogram(DefaultBuckets),
+	}
+}
+
+func (c *collector) OnEnqueue(e observer.QueueEvent) {
+	atomic.AddInt64(&c.enqueued, 1)
+}
+
+func (c *collector) OnDequeue(e observer.QueueEvent) {
+	atomic.AddInt64(&c.dequeued, 1)
+	c.queueWait.observe(e.Wait)
+}
+
+func (c *collector) OnDrop(e observer.QueueEvent) {
+	atomic.AddInt64(&c.dropped, 1)
+}
+
+func (c *collector) OnWorkerStart(e observer.WorkerEvent) {
+	atomic.AddInt64(&c.started, 1)
+	c.workerWait.observe(e.Wait)
+}
+
+func (c *collector) OnWorkerFinish(e observer.WorkerEvent) {
+	atomic.AddInt64(&c.finished, 1)
+	if e.Err != nil {
+		atomic.AddInt64(&c.failed, 1)
+	}
+	c.workerRun.observe(e.Run)
+}
+
+func (c *collector) OnWorkerPanic(e observer.WorkerEvent) {
+	atomic.AddInt64(&c.panics, 1)
+}
+
+func (c *collector) OnWorkerRetry(e observer.WorkerEvent) {
+	atomic.AddInt64(&c.retries, 1)
+}
+
+// histogram is cumulative histogram with atomic counters.
+type histogram struct {
+	bounds []float64
+	counts []int64 // counts[i] is num of observations <= bounds[i], the last is +Inf
+	sum    int64   // nanoseconds
+}
+
+func newHistogram(bounds []float64) *histogram {
+	return &histogram{
+		bounds: bounds,
+		counts: make([]int64, len(bounds)+1),
+	}
+}
+
+func (h *histogram) observe(d time.Duration) {
+	i := sort.SearchFloat64s(h.bounds, d.Seconds())
+	atomic.AddInt64(&h.counts[i], 1)
+	atomic.AddInt64(&h.sum, int64(d))
+}
+
+func (h *histogram) write(w io.Writer, name, labels string) {
+	var cumulative int64
+	for i, bound := range h.bounds {
+		cumulative += atomic.LoadInt64(&h.counts[i])
+		fmt.Fprintf(w, "%s_bucket{%s,le=\"%s\"} %d\n", name, labels, formatFloat(bound), cumulative)
+	}
+	cumulative += atomic.LoadInt64(&h.counts[len(h.bounds)])
+	fmt.Fprintf(w, "%s_bucket{%s,le=\"+Inf\"} %d\n", name, labels, cumulative)
+	fmt.Fprintf(w, "%s_sum{%s} %s\n", name, labels, formatFloat(time.Duration(atomic.LoadInt64(&h.sum)).Seconds()))
+	fmt.Fprintf(w, "%s_count{%s} %d\n", name, labels, cumulative)
+}
+
+type family struct {
+	name, help, typ string
+	write           func(w io.Writer, name string, c *snapshot)
+}
+
+var families = []family{
+	{"goutil_queue_length", "Number of elements in queue.", "gauge", func(w io.Writer, name string, c *snapshot) {
+		if c.queue != nil {
+			fmt.Fprintf(w, "%s{%s} %d\n", name, c.labels(), c.queue.Len())
+		}
+		if c.workerQueue != nil {
+			fmt.Fprintf(w, "%s{%s} %d\n", name, c.labels(), c.workerQueue.NumBacklogWorkers())
+		}
+	}},
+	{"goutil_queue_enqueued_total", "Total number of enqueued elements.", "counter", counter(func(c *collector) *int64 { return &c.enqueued })},
+	{"goutil_queue_dequeued_total", "Total number of dequeued elements.", "counter", counter(func(c *collector) *int64 { return &c.dequeued })},
+	{"goutil_queue_dropped_total", "Total number of elements dropped when queue destroyed.", "counter", counter(func(c *collector) *int64 { return &c.dropped })},
+	{"goutil_queue_wait_seconds", "Time elements stayed in queue.", "histogram", func(w io.Writer, name string, c *snapshot) {
+		c.queueWait.write(w, name, c.labels())
+	}},
+	{"goutil_workers", "Number of workers by state.", "gauge", func(w io.Writer, name string, c *snapshot) {
+		if c.workerQueue != nil {
+			fmt.Fprintf(w, "%s{%s,state=\"queued\"} %d\n", name, c.labels(), c.workerQueue.NumBacklogWorkers())
+			fmt.Fprintf(w, "%s{%s,state=\"running\"} %d\n", name, c.labels(), c.workerQueue.NumWorkingWorkers())
+		}
+	}},
+	{"goutil_worker_concurrency", "Max number of running workers.", "gauge", func(w io.Writer, name string, c *snapshot) {
+		if c.workerQueue != nil {
+			fmt.Fprintf(w, "%s{%s} %d\n", name, c.labels(), c.workerQueue.Concurrency())
+		}
+	}},
+	{"goutil_worker_started_total", "Total number of started workers.", "counter", counter(func(c *collector) *int64 { return &c.started })},
+	{"goutil_worker_finished_total", "Total number of finished workers.", "counter", counter(func(c *collector) *int64 { return &c.finished })},
+	{"goutil_worker_failed_total", "Total number of finished workers with error.", "counter", counter(func(c *collector) *int64 { return &c.failed })},
+	{"goutil_worker_panics_total", "Total number of panicked workers.", "counter", counter(func(c *collector) *int64 { return &c.panics })},
+	{"goutil_worker_retries_total", "Total number of worker retries.", "counter", counter(func(c *collector) *int64 { return &c.retries })},
+	{"goutil_worker_wait_seconds", "Time workers stayed in backlog.", "histogram", func(w io.Writer, name string, c *snapshot) {
+		c.workerWait.write(w, name, c.labels())
+	}},
+	{"goutil_worker_run_seconds", "Time workers processing.", "histogram", func(w io.Writer, name string, c *snapshot) {
+		c.workerRun.write(w, name, c.labels())
+	}},
+}
+
+func counter(field func(c *collector) *int64) func(w io.Writer, name string, c *snapshot) {
+	return func(w io.Writer, name string, c *snapshot) {
+		fmt.Fprintf(w, "%s{%s} %d\n", name, c.labels(), atomic.LoadInt64(field(c.collector)))
+	}
+}
+
+var labelReplacer = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+func (c *collector) labels() string {
+	return `queue="` + labelReplacer.Replace(c.name) + `"`
+}
+
+func formatFloat(f float64) string {
+	if math.IsInf(f, 1) {
+		return "+Inf"
+	}
+	return strconv.FormatFloat(f, 'g', -1, 64)
+}
+
+func withLock(lk sync.Locker, fn func()) {
+	lk.Lock()
+	defer lk.Unlock() // in case fn panics
+	fn()
+}
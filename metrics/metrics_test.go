@@ -0,0 +1,111 @@
+package metrics
+
+import (
+	"io"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/luweimy/goutil/syncq2"
+	"github.com/luweimy/goutil/workerq"
+)
+
+func TestRegistry(t *testing.T) {
+	r := NewRegistry()
+
+	q := syncq2.NewWithObserver(r.Observer("items"))
+	r.RegisterQueue("items", q)
+	q.Enqueue(1)
+	q.Enqueue(2)
+	q.Dequeue()
+
+	wq := workerq.NewWithObserver(2, r.Observer("jobs")).Start()
+	defer wq.Stop()
+	r.RegisterWorkerQueue("jobs", wq)
+	wq.AddWorkerFunc(nil, func(worker *workerq.Worker) error {
+		panic("job panic")
+	}).Wait()
+
+	srv := httptest.NewServer(r)
+	defer srv.Close()
+	resp, err := srv.Client().Get(srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(resp.Body)
+	text := string(body)
+
+	for _, line := range []string{
+		`# TYPE goutil_queue_length gauge`,
+		`goutil_queue_length{queue="items"} 1`,
+		`goutil_queue_enqueued_total{queue="items"} 2`,
+		`goutil_queue_dequeued_total{queue="items"} 1`,
+		`goutil_queue_wait_seconds_count{queue="items"} 1`,
+		`goutil_queue_enqueued_total{queue="jobs"} 1`,
+		`goutil_workers{queue="jobs",state="running"} 0`,
+		`goutil_worker_concurrency{queue="jobs"} 2`,
+		`goutil_worker_panics_total{queue="jobs"} 1`,
+		`goutil_worker_failed_total{queue="jobs"} 1`,
+		`goutil_worker_run_seconds_bucket{queue="jobs",le="+Inf"} 1`,
+	} {
+		if !strings.Contains(text, line+"\n") {
+			t.Errorf("except line %q in:\n%s", line, text)
+		}
+	}
+	if strings.Count(text, "# TYPE goutil_worker_run_seconds histogram") != 1 {
+		t.Error("except metric family is written once")
+	}
+}
+
+func TestRegistry_Resizing(t *testing.T) {
+	r := NewRegistry()
+	wq := workerq.NewWithObserver(1, r.Observer("jobs")).Start()
+	defer wq.Stop()
+	r.RegisterWorkerQueue("jobs", wq)
+
+	release := make(chan struct{})
+	started := make(chan struct{})
+	wq.AddWorkerFunc(nil, func(worker *workerq.Worker) error {
+		close(started)
+		<-release
+		return nil
+	})
+	<-started
+	go wq.SetConcurrency(2)
+	time.Sleep(time.Millisecond * 20) // SetConcurrency waits for the running worker
+
+	// scraping and registration are not blocked while SetConcurrency waiting.
+	done := make(chan string)
+	go func() {
+		r.RegisterQueue("other", syncq2.New())
+		buf := &strings.Builder{}
+		r.WriteTo(buf)
+		done <- buf.String()
+	}()
+	select {
+	case text := <-done:
+		if s := `goutil_worker_concurrency{queue="jobs"} 1`; !strings.Contains(text, s) {
+			t.Errorf("except %q in %s", s, text)
+		}
+	case <-time.After(time.Second):
+		t.Error("except scrape not blocked")
+	}
+	close(release)
+}
+
+func TestRegistry_Replace(t *testing.T) {
+	r := NewRegistry()
+	r.RegisterQueue("x", syncq2.New())
+	r.RegisterWorkerQueue("x", workerq.New(1))
+	buf := &strings.Builder{}
+	r.WriteTo(buf)
+	// series of a name is emitted once.
+	if n := strings.Count(buf.String(), `goutil_queue_length{queue="x"}`); n != 1 {
+		t.Errorf("except 1 series, actual %d", n)
+	}
+	if !strings.Contains(buf.String(), `goutil_worker_concurrency{queue="x"} 1`) {
+		t.Errorf("except worker queue replaced queue, actual %s", buf.String())
+	}
+}
@@ -0,0 +1,119 @@
+package observer
+
+import (
+	"time"
+)
+
+// Observer receive events of syncq, syncq2 and workerq.
+// callbacks are called synchronously on the hot path, so they must be cheap and never block.
+// implementations should embed Nop, so they keep compatible when new events added.
+type Observer interface {
+	OnEnqueue(e QueueEvent)
+	OnDequeue(e QueueEvent)
+	OnDrop(e QueueEvent)
+
+	OnWorkerStart(e WorkerEvent)
+	OnWorkerFinish(e WorkerEvent)
+	OnWorkerPanic(e WorkerEvent)
+	OnWorkerRetry(e WorkerEvent)
+}
+
+// QueueEvent is event of queue element.
+type QueueEvent struct {
+	Time time.Time
+	Len  int           // queue length after the event
+	Wait time.Duration // time the element stayed in queue, zero on enqueue
+}
+
+// WorkerEvent is event of worker processed by WorkerQueue.
+type WorkerEvent struct {
+	Time     time.Time
+	WorkerID uint64
+	Wait     time.Duration // time the worker stayed in backlog
+	Run      time.Duration // processing duration, only on finish, panic and retry
+	Err      error         // error of worker on finish, error of the failed attempt on retry
+	Panic    interface{}   // recovered value, only on panic
+	Stack    []byte        // stack of panic, only on panic
+	Attempt  int           // num of the next attempt, only on retry
+}
+
+// Nop is observer ignores all events.
+type Nop struct{}
+
+func (Nop) OnEnqueue(e QueueEvent)       {}
+func (Nop) OnDequeue(e QueueEvent)       {}
+func (Nop) OnDrop(e QueueEvent)          {}
+func (Nop) OnWorkerStart(e WorkerEvent)  {}
+func (Nop) OnWorkerFinish(e WorkerEvent) {}
+func (Nop) OnWorkerPanic(e WorkerEvent)  {}
+func (Nop) OnWorkerRetry(e WorkerEvent)  {}
+
+// Enabled report whether obs observes anything, queues skip collecting events if not.
+func Enabled(obs Observer) bool {
+	switch obs.(type) {
+	case nil, Nop, *Nop:
+		return false
+	}
+	return true
+}
+
+// Multi fan out events to observers.
+func Multi(observers ...Observer) Observer {
+	enabled := make(multi, 0, len(observers))
+	for _, obs := range observers {
+		if Enabled(obs) {
+			enabled = append(enabled, obs)
+		}
+	}
+	switch len(enabled) {
+	case 0:
+		return Nop{}
+	case 1:
+		return enabled[0]
+	}
+	return enabled
+}
+
+type multi []Observer
+
+func (m multi) OnEnqueue(e QueueEvent) {
+	for _, obs := range m {
+		obs.OnEnqueue(e)
+	}
+}
+
+func (m multi) OnDequeue(e QueueEvent) {
+	for _, obs := range m {
+		obs.OnDequeue(e)
+	}
+}
+
+func (m multi) OnDrop(e QueueEvent) {
+	for _, obs := range m {
+		obs.OnDrop(e)
+	}
+}
+
+func (m multi) OnWorkerStart(e WorkerEvent) {
+	for _, obs := range m {
+		obs.OnWorkerStart(e)
+	}
+}
+
+func (m multi) OnWorkerFinish(e WorkerEvent) {
+	for _, obs := range m {
+		obs.OnWorkerFinish(e)
+	}
+}
+
+func (m multi) OnWorkerPanic(e WorkerEvent) {
+	for _, obs := range m {
+		obs.OnWorkerPanic(e)
+	}
+}
+
+func (m multi) OnWorkerRetry(e WorkerEvent) {
+	for _, obs := range m {
+		obs.OnWorkerRetry(e)
+	}
+}
@@ -0,0 +1,29 @@
+package observer
+
+import (
+	"testing"
+)
+
+type countObserver struct {
+	Nop
+	n int
+}
+
+func (o *countObserver) OnEnqueue(e QueueEvent) { o.n++ }
+
+func TestMulti(t *testing.T) {
+	if Enabled(Multi(nil, Nop{}, &Nop{})) {
+		t.Error("except disabled observer")
+	}
+
+	o1, o2 := &countObserver{}, &countObserver{}
+	if obs := Multi(o1, nil); obs != o1 {
+		t.Errorf("except single observer, actual %v", obs)
+	}
+	obs := Multi(o1, Nop{}, o2)
+	obs.OnEnqueue(QueueEvent{})
+	obs.OnDequeue(QueueEvent{})
+	if o1.n != 1 || o2.n != 1 {
+		t.Errorf("except 1 1, actual %v %v", o1.n, o2.n)
+	}
+}
@@ -0,0 +1,218 @@
+package pipeline
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+
+	"github.com/luweimy/goutil/syncq"
+	"github.com/luweimy/goutil/workerq"
+)
+
+// ErrClosed is returned when push items to closed pipeline.
+var ErrClosed = errors.New("pipeline: closed")
+
+// StageFunc process an item, the returned output is pushed to the next stage,
+// nil output is dropped, so StageFunc can be used as filter too.
+type StageFunc func(worker *workerq.Worker, item interface{}) (interface{}, error)
+
+// ErrorFunc receive errors(and panics) of stages with the item failed.
+type ErrorFunc func(stage string, item interface{}, err error)
+
+// Stage describe a stage of pipeline.
+type Stage struct {
+	Name        string
+	Concurrency int // max processing items of the stage, default 1
+	Buffer      int // size of input buffer of the stage, default Concurrency
+	Func        StageFunc
+}
+
+// StageStats is metrics of a stage.
+type StageStats struct {
+	Name     string
+	In       int64 // num of items taken from buffer
+	Out      int64 // num of items pushed to next stage
+	Errors   int64
+	Buffered int64 // num of items in buffer
+	Running  int   // num of processing items
+}
+
+// endOfStream is pushed through stages to flush them in order.
+type endOfStream struct{}
+
+type stage struct {
+	Stage
+	in   *syncq.SyncQueue     // bounded input buffer
+	q    *workerq.WorkerQueue // process items with stage concurrency
+	sem  chan struct{}        // limit processing items, so buffer can block upstream
+	wg   sync.WaitGroup
+	next *stage
+
+	pushed int64
+	taken  int64
+	out    int64
+	errs   int64
+}
+
+// Pipeline chain stages with bounded buffers, each stage is processed by its own WorkerQueue.
+// a full buffer blocks the upstream stage, and finally blocks Push.
+type Pipeline struct {
+	stages  []*stage
+	onError ErrorFunc
+
+	mu     sync.RWMutex // rlock during pushing, in case of push after close
+	closed bool
+	done   chan struct{} // closed when the last stage flushed
+}
+
+// New create and start pipeline of stages, onError can be nil.
+func New(onError ErrorFunc, stages ...Stage) *Pipeline {
+	p := &Pipeline{
+		onError: onError,
+		done:    make(chan struct{}),
+	}
+	for _, st := range stages {
+		if st.Concurrency <= 0 {
+			st.Concurrency = 1
+		}
+		if st.Buffer <= 0 {
+			st.Buffer = st.Concurrency
+		}
+		p.stages = append(p.stages, &stage{
+			Stage: st,
+			in:    syncq.NewWithSize(st.Buffer),
+			q:     workerq.New(st.Concurrency).Start(),
+			sem:   make(chan struct{}, st.Concurrency),
+		})
+	}
+	for i := 0; i+1 < len(p.stages); i++ {
+		p.stages[i].next = p.stages[i+1]
+	}
+	if len(p.stages) == 0 {
+		close(p.done)
+	}
+	for _, s := range p.stages {
+		go p.run(s)
+	}
+	return p
+}
+
+// Push push item to the first stage, it blocks when the buffer of first stage is full.
+func (p *Pipeline) Push(item interface{}) error {
+	var err error
+	withLock(p.mu.RLocker(), func() {
+		if p.closed {
+			err = ErrClosed
+			return
+		}
+		if len(p.stages) > 0 {
+			p.stages[0].push(item)
+		}
+	})
+	return err
+}
+
+// Close stop intake, and block until all pushed items flushed through stages in order.
+func (p *Pipeline) Close() {
+	withLock(&p.mu, func() {
+		if p.closed {
+			return
+		}
+		p.closed = true
+		if len(p.stages) > 0 {
+			p.stages[0].push(endOfStream{})
+		}
+	})
+	<-p.done
+}
+
+// Done is closed when the pipeline closed and flushed.
+func (p *Pipeline) Done() <-chan struct{} {
+	return p.done
+}
+
+// Stats return metrics of stages in order.
+func (p *Pipeline) Stats() []StageStats {
+	stats := make([]StageStats, 0, len(p.stages))
+	for _, s := range p.stages {
+		in := atomic.LoadInt64(&s.taken)
+		stats = append(stats, StageStats{
+			Name:     s.Name,
+			In:       in,
+			Out:      atomic.LoadInt64(&s.out),
+			Errors:   atomic.LoadInt64(&s.errs),
+			Buffered: atomic.LoadInt64(&s.pushed) - in,
+			Running:  s.q.NumWorkingWorkers(),
+		})
+	}
+	return stats
+}
+
+func (p *Pipeline) run(s *stage) {
+	for {
+		// acquire before dequeue, so no item is held out of buffer while stage is busy.
+		s.sem <- struct{}{}
+		item := s.in.Dequeue()
+		if _, ok := item.(endOfStream); ok {
+			// all upstream items are pushed, flush the stage and then the next.
+			<-s.sem
+			s.wg.Wait()
+			s.q.Stop()
+			s.in.Destroy()
+			if s.next != nil {
+				s.next.push(item)
+			} else {
+				close(p.done)
+			}
+			return
+		}
+		atomic.AddInt64(&s.taken, 1)
+
+		s.wg.Add(1)
+		s.q.AddWorkerFunc(nil, func(worker *workerq.Worker) error {
+			defer func() {
+				<-s.sem
+				s.wg.Done()
+			}()
+			return p.process(s, worker, item)
+		})
+	}
+}
+
+func (p *Pipeline) process(s *stage, worker *workerq.Worker, item interface{}) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("panic: %v", r)
+		}
+		if err != nil {
+			atomic.AddInt64(&s.errs, 1)
+			if p.onError != nil {
+				p.onError(s.Name, item, err)
+			}
+		}
+	}()
+
+	out, err := s.Func(worker, item)
+	if err != nil || out == nil {
+		return err
+	}
+	atomic.AddInt64(&s.out, 1)
+	if s.next != nil {
+		s.next.push(out)
+	}
+	return nil
+}
+
+func (s *stage) push(item interface{}) {
+	if _, ok := item.(endOfStream); !ok {
+		atomic.AddInt64(&s.pushed, 1)
+	}
+	s.in.Enqueue(item)
+}
+
+func withLock(lk sync.Locker, fn func()) {
+	lk.Lock()
+	defer lk.Unlock() // in case fn panics
+	fn()
+}
@@ -0,0 +1,104 @@
+package pipeline
+
+import (
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/luweimy/goutil/workerq"
+)
+
+func TestPipeline(t *testing.T) {
+	var (
+		sum    int64
+		mu     sync.Mutex
+		failed []interface{}
+	)
+	errNegative := errors.New("negative")
+
+	p := New(func(stage string, item interface{}, err error) {
+		withLock(&mu, func() {
+			failed = append(failed, item)
+		})
+		if stage != "parse" || err != errNegative {
+			t.Errorf("except parse %v, actual %v %v", errNegative, stage, err)
+		}
+	}, Stage{
+		Name:        "fetch",
+		Concurrency: 4,
+		Func: func(worker *workerq.Worker, item interface{}) (interface{}, error) {
+			time.Sleep(time.Millisecond * 10)
+			return item, nil
+		},
+	}, Stage{
+		Name:        "parse",
+		Concurrency: 2,
+		Func: func(worker *workerq.Worker, item interface{}) (interface{}, error) {
+			if item.(int) < 0 {
+				return nil, errNegative
+			}
+			return item.(int) * 2, nil
+		},
+	}, Stage{
+		Name:        "write",
+		Concurrency: 1,
+		Func: func(worker *workerq.Worker, item interface{}) (interface{}, error) {
+			atomic.AddInt64(&sum, int64(item.(int)))
+			return nil, nil
+		},
+	})
+
+	for i := 1; i <= 10; i++ {
+		p.Push(i)
+	}
+	p.Push(-1)
+	p.Close()
+
+	if sum != 110 {
+		t.Errorf("except 110, actual %v", sum)
+	}
+	if len(failed) != 1 || failed[0] != -1 {
+		t.Errorf("except failed [-1], actual %v", failed)
+	}
+	if err := p.Push(1); err != ErrClosed {
+		t.Errorf("except %v, actual %v", ErrClosed, err)
+	}
+
+	stats := p.Stats()
+	if stats[0].In != 11 || stats[1].Errors != 1 || stats[2].In != 10 || stats[2].Buffered != 0 {
+		t.Errorf("stats not except, %+v", stats)
+	}
+}
+
+func TestPipeline_Backpressure(t *testing.T) {
+	release := make(chan struct{})
+	p := New(nil, Stage{
+		Name:        "slow",
+		Concurrency: 1,
+		Buffer:      1,
+		Func: func(worker *workerq.Worker, item interface{}) (interface{}, error) {
+			<-release
+			return nil, nil
+		},
+	})
+
+	pushed := make(chan struct{})
+	go func() {
+		for i := 0; i < 3; i++ {
+			p.Push(i)
+		}
+		close(pushed)
+	}()
+
+	// one item processing and one buffered, the third push is blocked.
+	select {
+	case <-pushed:
+		t.Error("push is not blocked by full buffer")
+	case <-time.After(time.Millisecond * 200):
+	}
+	close(release)
+	<-pushed
+	p.Close()
+}
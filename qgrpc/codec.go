@@ -0,0 +1,85 @@
+package qgrpc
+
+import (
+	"encoding/json"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/encoding"
+)
+
+// codecName is content subtype of the JSON codec, messages are encoded in JSON so no protoc is needed.
+// it is specific to this package so that the codec registered globally does not replace codecs of others.
+const codecName = "qgrpc-json"
+
+const connectMethod = "/goutil.qgrpc.Executor/Connect"
+
+func init() {
+	encoding.RegisterCodec(jsonCodec{})
+}
+
+type jsonCodec struct{}
+
+func (jsonCodec) Marshal(v interface{}) ([]byte, error) {
+	return json.Marshal(v)
+}
+
+func (jsonCodec) Unmarshal(data []byte, v interface{}) error {
+	return json.Unmarshal(data, v)
+}
+
+func (jsonCodec) Name() string {
+	return codecName
+}
+
+// ExecutorMessage is sent by executor on the stream, the first message must carry Hello.
+type ExecutorMessage struct {
+	Hello     *Hello  `json:"hello,omitempty"`
+	Pull      int     `json:"pull,omitempty"` // num of more jobs the executor can run
+	Heartbeat bool    `json:"heartbeat,omitempty"`
+	Result    *Result `json:"result,omitempty"`
+}
+
+type Hello struct {
+	ExecutorID string   `json:"executor_id"`
+	Handlers   []string `json:"handlers"` // names of handlers the executor can run
+}
+
+// Result is result of a job, Error is not empty if job failed.
+type Result struct {
+	JobID  uint64          `json:"job_id"`
+	Output json.RawMessage `json:"output,omitempty"`
+	Error  string          `json:"error,omitempty"`
+}
+
+// CoordinatorMessage is sent by coordinator on the stream.
+type CoordinatorMessage struct {
+	Job    *Job   `json:"job,omitempty"`
+	Cancel uint64 `json:"cancel,omitempty"` // id of job canceled, its result is ignored
+}
+
+type Job struct {
+	ID      uint64          `json:"id"`
+	Handler string          `json:"handler"`
+	Payload json.RawMessage `json:"payload,omitempty"`
+}
+
+// executorServer is implemented by Coordinator.
+type executorServer interface {
+	connect(stream grpc.ServerStream) error
+}
+
+// serviceDesc is written by hand instead of generated by protoc, the only method is a bidirectional stream.
+var serviceDesc = grpc.ServiceDesc{
+	ServiceName: "goutil.qgrpc.Executor",
+	HandlerType: (*executorServer)(nil),
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName: "Connect",
+			Handler: func(srv interface{}, stream grpc.ServerStream) error {
+				return srv.(executorServer).connect(stream)
+			},
+			ServerStreams: true,
+			ClientStreams: true,
+		},
+	},
+}
@@ -0,0 +1,31 @@
+package qgrpc
+
+import (
+	"testing"
+
+	"google.golang.org/grpc/encoding"
+)
+
+func TestJSONCodec(t *testing.T) {
+	codec := jsonCodec{}
+	data, err := codec.Marshal(&CoordinatorMessage{Job: &Job{ID: 1, Handler: "h", Payload: []byte(`{"x":1}`)}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s := string(data); s != `{"job":{"id":1,"handler":"h","payload":{"x":1}}}` {
+		t.Errorf("except job message, actual %s", s)
+	}
+	var m CoordinatorMessage
+	if err := codec.Unmarshal(data, &m); err != nil || m.Job.Handler != "h" || m.Cancel != 0 {
+		t.Errorf("except job message, actual %+v %v", m, err)
+	}
+}
+
+func TestJSONCodec_Registered(t *testing.T) {
+	if _, ok := encoding.GetCodec(codecName).(jsonCodec); !ok {
+		t.Errorf("except jsonCodec registered as %s", codecName)
+	}
+	if _, ok := encoding.GetCodec("json").(jsonCodec); ok {
+		t.Error("except codec named json not replaced")
+	}
+}
@@ -0,0 +1,304 @@
+package qgrpc
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/luweimy/goutil/workerq"
+)
+
+// DefaultHeartbeatTimeout is time an executor may stay silent before it is disconnected.
+const DefaultHeartbeatTimeout = time.Second * 30
+
+// Coordinator hold backlog of jobs in a WorkerQueue and distribute them to remote executors
+// connected over bidirectional gRPC streams. concurrency of the queue limits jobs running on all executors.
+// jobs on disconnected executors are dispatched again to other executors.
+type Coordinator struct {
+	ctx              context.Context
+	cancel           context.CancelFunc
+	q                *workerq.WorkerQueue
+	heartbeatTimeout time.Duration
+
+	mu        sync.Mutex
+	executors map[*executor]struct{}
+	changed   chan struct{} // closed when executors or their credits changed
+	logger    atomic.Pointer[slog.Logger]
+}
+
+type executor struct {
+	id       string
+	handlers map[string]bool
+	send     chan *CoordinatorMessage
+	done     chan struct{} // closed when disconnected
+	seen     int64         // unix nano of last message, accessed atomically
+
+	// guarded by mu of coordinator
+	credits int                     // num of jobs pulled but not assigned
+	jobs    map[uint64]chan *Result // running jobs
+}
+
+// NewCoordinator create Coordinator runs at most concurrency jobs across all executors.
+func NewCoordinator(concurrency int) *Coordinator {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &Coordinator{
+		ctx:              ctx,
+		cancel:           cancel,
+		q:                workerq.New(concurrency).SetName("qgrpc").Start(),
+		heartbeatTimeout: DefaultHeartbeatTimeout,
+		executors:        make(map[*executor]struct{}),
+		changed:          make(chan struct{}),
+	}
+}
+
+// SetHeartbeatTimeout set time an executor may stay silent before it is disconnected, it should be called before Register.
+// non-positive timeout means DefaultHeartbeatTimeout, timeout less than 1ms is raised to 1ms.
+func (c *Coordinator) SetHeartbeatTimeout(timeout time.Duration) *Coordinator {
+	if timeout <= 0 {
+		timeout = DefaultHeartbeatTimeout
+	}
+	c.heartbeatTimeout = max(timeout, time.Millisecond)
+	return c
+}
+
+// SetLogger log connections of executors and redispatching of jobs to l, nil disable logging.
+func (c *Coordinator) SetLogger(l *slog.Logger) *Coordinator {
+	c.logger.Store(l)
+	return c
+}
+
+// Queue return queue holds the backlog, use it to change concurrency, observe or diagnose jobs.
+func (c *Coordinator) Queue() *workerq.WorkerQueue {
+	return c.q
+}
+
+// Register register the executor service to s.
+func (c *Coordinator) Register(s *grpc.Server) {
+	s.RegisterService(&serviceDesc, c)
+}
+
+// Submit add job run by handler of remote executors with payload encoded to JSON.
+// result of the worker is json.RawMessage output of handler. cancel the worker to cancel the remote job.
+func (c *Coordinator) Submit(ctx context.Context, handler string, payload interface{}) (*workerq.Worker, error) {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return nil, err
+	}
+	worker := workerq.NewWorker(ctx, c.dispatch(handler, data)).SetName(handler)
+	c.q.AddWorker(worker)
+	return worker, nil
+}
+
+// Stop stop the queue and disconnect executors, running jobs are requeued and never finished.
+func (c *Coordinator) Stop() {
+	c.cancel()
+	c.q.Stop()
+}
+
+// dispatch return WorkerFunc runs job on a remote executor, and dispatches it again if executor disconnected.
+func (c *Coordinator) dispatch(handler string, payload json.RawMessage) workerq.WorkerFunc {
+	return func(worker *workerq.Worker) error {
+		ctx := worker.Context()
+		job := &CoordinatorMessage{Job: &Job{ID: worker.ID(), Handler: handler, Payload: payload}}
+		for {
+			ex, res, err := c.acquire(ctx, handler, job.Job.ID)
+			if err != nil {
+				return err
+			}
+			select {
+			case ex.send <- job:
+			case <-ex.done:
+				c.log(slog.LevelInfo, "job redispatched", slog.Uint64("job", job.Job.ID), slog.String("executor", ex.id))
+				continue
+			case <-ctx.Done():
+				c.unsend(ex, job.Job.ID)
+				return ctx.Err()
+			}
+
+			select {
+			case r := <-res:
+				return c.finish(worker, r)
+			case <-ex.done:
+				select {
+				case r := <-res: // result arrived before disconnected
+					return c.finish(worker, r)
+				default:
+				}
+				c.log(slog.LevelInfo, "job redispatched", slog.Uint64("job", job.Job.ID), slog.String("executor", ex.id))
+			case <-ctx.Done():
+				c.release(ex, job.Job.ID)
+				select {
+				case ex.send <- &CoordinatorMessage{Cancel: job.Job.ID}:
+				case <-ex.done:
+				}
+				return ctx.Err()
+			}
+		}
+	}
+}
+
+func (c *Coordinator) finish(worker *workerq.Worker, r *Result) error {
+	if r.Error != "" {
+		return errors.New(r.Error)
+	}
+	worker.SetResult(r.Output)
+	return nil
+}
+
+// acquire take a credit of executor can run handler, block until one pulled or ctx done.
+func (c *Coordinator) acquire(ctx context.Context, handler string, id uint64) (*executor, chan *Result, error) {
+	for {
+		var (
+			ex      *executor
+			res     chan *Result
+			changed chan struct{}
+		)
+		withLock(&c.mu, func() {
+			for e := range c.executors {
+				if e.credits > 0 && e.handlers[handler] {
+					e.credits--
+					ex, res = e, make(chan *Result, 1)
+					e.jobs[id] = res
+					return
+				}
+			}
+			changed = c.changed
+		})
+		if ex != nil {
+			return ex, res, nil
+		}
+		select {
+		case <-changed:
+		case <-ctx.Done():
+			return nil, nil, ctx.Err()
+		}
+	}
+}
+
+// unsend give back credit taken by acquire for job never sent to executor.
+func (c *Coordinator) unsend(ex *executor, id uint64) {
+	withLock(&c.mu, func() {
+		delete(ex.jobs, id)
+		ex.credits++
+		c.broadcast()
+	})
+}
+
+// release forget job sent to executor, its result will be ignored.
+func (c *Coordinator) release(ex *executor, id uint64) {
+	withLock(&c.mu, func() {
+		delete(ex.jobs, id)
+	})
+}
+
+// broadcast must be called with mu held.
+func (c *Coordinator) broadcast() {
+	close(c.changed)
+	c.changed = make(chan struct{})
+}
+
+func (c *Coordinator) connect(stream grpc.ServerStream) error {
+	var hello ExecutorMessage
+	if err := stream.RecvMsg(&hello); err != nil {
+		return err
+	}
+	if hello.Hello == nil {
+		return status.Error(codes.InvalidArgument, "qgrpc: first message must be hello")
+	}
+	ex := &executor{
+		id:       hello.Hello.ExecutorID,
+		handlers: make(map[string]bool),
+		send:     make(chan *CoordinatorMessage),
+		done:     make(chan struct{}),
+		jobs:     make(map[uint64]chan *Result),
+		seen:     time.Now().UnixNano(),
+	}
+	for _, h := range hello.Hello.Handlers {
+		ex.handlers[h] = true
+	}
+	withLock(&c.mu, func() {
+		c.executors[ex] = struct{}{}
+	})
+	c.handle(ex, &hello) // hello may pull jobs
+	c.log(slog.LevelInfo, "executor connected", slog.String("executor", ex.id), slog.Any("handlers", hello.Hello.Handlers))
+	defer func() {
+		withLock(&c.mu, func() {
+			delete(c.executors, ex)
+			close(ex.done)
+			c.broadcast()
+		})
+		c.log(slog.LevelInfo, "executor disconnected", slog.String("executor", ex.id))
+	}()
+
+	// receive in another goroutine, it returns once stream done since handler returned.
+	errc := make(chan error, 1)
+	go func() {
+		for {
+			var m ExecutorMessage
+			if err := stream.RecvMsg(&m); err != nil {
+				errc <- err
+				return
+			}
+			c.handle(ex, &m)
+		}
+	}()
+
+	ticker := time.NewTicker(c.heartbeatTimeout / 4)
+	defer ticker.Stop()
+	for {
+		select {
+		case m := <-ex.send:
+			if err := stream.SendMsg(m); err != nil {
+				return err
+			}
+		case err := <-errc:
+			if err == io.EOF {
+				return nil
+			}
+			return err
+		case <-ticker.C:
+			if time.Since(time.Unix(0, atomic.LoadInt64(&ex.seen))) > c.heartbeatTimeout {
+				return status.Error(codes.DeadlineExceeded, "qgrpc: executor heartbeat timeout")
+			}
+		case <-c.ctx.Done():
+			return status.Error(codes.Unavailable, "qgrpc: coordinator stopped")
+		}
+	}
+}
+
+func (c *Coordinator) handle(ex *executor, m *ExecutorMessage) {
+	atomic.StoreInt64(&ex.seen, time.Now().UnixNano())
+	withLock(&c.mu, func() {
+		if r := m.Result; r != nil {
+			if res := ex.jobs[r.JobID]; res != nil {
+				delete(ex.jobs, r.JobID)
+				res <- r
+			}
+		}
+		if m.Pull > 0 {
+			ex.credits += m.Pull
+			c.broadcast()
+		}
+	})
+}
+
+func (c *Coordinator) log(level slog.Level, msg string, args ...interface{}) {
+	if l := c.logger.Load(); l != nil {
+		l.Log(context.Background(), level, msg, args...)
+	}
+}
+
+func withLock(lk sync.Locker, fn func()) {
+	lk.Lock()
+	defer lk.Unlock() // in case fn panics
+	fn()
+}
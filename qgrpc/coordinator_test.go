@@ -0,0 +1,187 @@
+package qgrpc
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+// serve start grpc server of c over in-memory listener, return client connection to it.
+func serve(t *testing.T, c *Coordinator) *grpc.ClientConn {
+	l := bufconn.Listen(1 << 20)
+	s := grpc.NewServer()
+	c.Register(s)
+	go s.Serve(l)
+	t.Cleanup(s.Stop)
+
+	cc, err := grpc.NewClient("passthrough:///bufconn",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return l.DialContext(ctx)
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { cc.Close() })
+	return cc
+}
+
+func TestCoordinator(t *testing.T) {
+	c := NewCoordinator(2)
+	defer c.Stop()
+	cc := serve(t, c)
+
+	var running, maxRunning int32
+	square := func(ctx context.Context, payload json.RawMessage) (interface{}, error) {
+		n := atomic.AddInt32(&running, 1)
+		defer atomic.AddInt32(&running, -1)
+		for {
+			m := atomic.LoadInt32(&maxRunning)
+			if n <= m || atomic.CompareAndSwapInt32(&maxRunning, m, n) {
+				break
+			}
+		}
+		time.Sleep(time.Millisecond * 20)
+		var x int
+		json.Unmarshal(payload, &x)
+		if x < 0 {
+			return nil, errors.New("negative")
+		}
+		return x * x, nil
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	for _, id := range []string{"a", "b"} {
+		go NewExecutor(id, 4).Handle("square", square).Run(ctx, cc)
+	}
+
+	var workers = make([]interface{ Wait() error }, 0)
+	results := make([]func() interface{}, 0)
+	for i := 1; i <= 6; i++ {
+		worker, err := c.Submit(context.Background(), "square", i)
+		if err != nil {
+			t.Fatal(err)
+		}
+		workers = append(workers, worker)
+		results = append(results, worker.Result)
+	}
+	for i, worker := range workers {
+		if err := worker.Wait(); err != nil {
+			t.Fatal(err)
+		}
+		var v int
+		json.Unmarshal(results[i]().(json.RawMessage), &v)
+		if v != (i+1)*(i+1) {
+			t.Errorf("except %d, actual %d", (i+1)*(i+1), v)
+		}
+	}
+	if m := atomic.LoadInt32(&maxRunning); m != 2 {
+		t.Errorf("except global concurrency 2, actual %d", m)
+	}
+
+	worker, _ := c.Submit(context.Background(), "square", -1)
+	if err := worker.Wait(); err == nil || err.Error() != "negative" {
+		t.Errorf("except negative, actual %v", err)
+	}
+}
+
+func TestCoordinator_Redispatch(t *testing.T) {
+	c := NewCoordinator(1)
+	defer c.Stop()
+	cc := serve(t, c)
+
+	started := make(chan struct{})
+	hang := func(ctx context.Context, payload json.RawMessage) (interface{}, error) {
+		close(started)
+		<-ctx.Done()
+		return nil, ctx.Err()
+	}
+	ctxA, cancelA := context.WithCancel(context.Background())
+	doneA := make(chan error)
+	go func() {
+		doneA <- NewExecutor("a", 1).Handle("job", hang).Run(ctxA, cc)
+	}()
+
+	worker, _ := c.Submit(context.Background(), "job", nil)
+	<-started
+	// executor b connects after a took the job, then a disconnects.
+	ctxB, cancelB := context.WithCancel(context.Background())
+	defer cancelB()
+	go NewExecutor("b", 1).Handle("job", func(ctx context.Context, payload json.RawMessage) (interface{}, error) {
+		return "b", nil
+	}).Run(ctxB, cc)
+	time.Sleep(time.Millisecond * 20)
+	cancelA()
+	if err := <-doneA; err != context.Canceled {
+		t.Errorf("except %v, actual %v", context.Canceled, err)
+	}
+
+	if err := worker.Wait(); err != nil {
+		t.Fatal(err)
+	}
+	if v := string(worker.Result().(json.RawMessage)); v != `"b"` {
+		t.Errorf("except b, actual %s", v)
+	}
+}
+
+func TestCoordinator_Cancel(t *testing.T) {
+	c := NewCoordinator(1)
+	defer c.Stop()
+	cc := serve(t, c)
+
+	canceled := make(chan struct{})
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go NewExecutor("a", 1).Handle("job", func(ctx context.Context, payload json.RawMessage) (interface{}, error) {
+		<-ctx.Done()
+		close(canceled)
+		return nil, ctx.Err()
+	}).Run(ctx, cc)
+
+	worker, _ := c.Submit(context.Background(), "job", nil)
+	time.Sleep(time.Millisecond * 50)
+	worker.Cancel()
+	select {
+	case <-canceled:
+	case <-time.After(time.Second):
+		t.Error("except remote job canceled")
+	}
+}
+
+func TestCoordinator_CancelUnsent(t *testing.T) {
+	c := NewCoordinator(1)
+	defer c.Stop()
+	// executor pulled a job but never receives it.
+	ex := &executor{
+		id:       "a",
+		handlers: map[string]bool{"job": true},
+		send:     make(chan *CoordinatorMessage),
+		done:     make(chan struct{}),
+		jobs:     make(map[uint64]chan *Result),
+		credits:  1,
+	}
+	withLock(&c.mu, func() {
+		c.executors[ex] = struct{}{}
+	})
+
+	worker, _ := c.Submit(context.Background(), "job", nil)
+	time.Sleep(time.Millisecond * 20)
+	worker.Cancel()
+	c.Queue().WaitIdle(context.Background())
+
+	var credits, jobs int
+	withLock(&c.mu, func() {
+		credits, jobs = ex.credits, len(ex.jobs)
+	})
+	if credits != 1 || jobs != 0 {
+		t.Errorf("except credit given back, actual credits %d jobs %d", credits, jobs)
+	}
+}
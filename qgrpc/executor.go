@@ -0,0 +1,166 @@
+package qgrpc
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+
+	"google.golang.org/grpc"
+)
+
+// DefaultHeartbeatInterval is interval of heartbeats sent by executor.
+const DefaultHeartbeatInterval = time.Second * 5
+
+// Handler run a job of payload, the output is encoded to JSON as result.
+type Handler func(ctx context.Context, payload json.RawMessage) (interface{}, error)
+
+// Executor connect to Coordinator and run jobs of its handlers, at most concurrency jobs run at the same time.
+type Executor struct {
+	id          string
+	concurrency int
+	heartbeat   time.Duration
+	handlers    map[string]Handler
+}
+
+func NewExecutor(id string, concurrency int) *Executor {
+	if concurrency <= 0 {
+		concurrency = 1
+	}
+	return &Executor{
+		id:          id,
+		concurrency: concurrency,
+		heartbeat:   DefaultHeartbeatInterval,
+		handlers:    make(map[string]Handler),
+	}
+}
+
+// Handle register h to run jobs of name, it should be called before Run.
+func (e *Executor) Handle(name string, h Handler) *Executor {
+	e.handlers[name] = h
+	return e
+}
+
+// SetHeartbeatInterval set interval of heartbeats, it must be less than heartbeat timeout of coordinator.
+// non-positive interval means DefaultHeartbeatInterval.
+func (e *Executor) SetHeartbeatInterval(interval time.Duration) *Executor {
+	if interval <= 0 {
+		interval = DefaultHeartbeatInterval
+	}
+	e.heartbeat = interval
+	return e
+}
+
+// Run connect to coordinator through cc and run jobs until ctx done or stream broken,
+// running jobs are canceled and waited before it returns. call Run again to reconnect.
+func (e *Executor) Run(ctx context.Context, cc grpc.ClientConnInterface) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	stream, err := cc.NewStream(ctx, &serviceDesc.Streams[0], connectMethod, grpc.CallContentSubtype(codecName))
+	if err != nil {
+		return err
+	}
+
+	var (
+		sendMu sync.Mutex // SendMsg is not safe to call concurrently
+		wg     sync.WaitGroup
+		jobsMu sync.Mutex
+		jobs   = make(map[uint64]context.CancelFunc)
+	)
+	send := func(m *ExecutorMessage) error {
+		var err error
+		withLock(&sendMu, func() {
+			err = stream.SendMsg(m)
+		})
+		return err
+	}
+	defer wg.Wait()
+	defer cancel() // cancel running jobs before waiting them
+
+	handlers := make([]string, 0, len(e.handlers))
+	for name := range e.handlers {
+		handlers = append(handlers, name)
+	}
+	if err := send(&ExecutorMessage{Hello: &Hello{ExecutorID: e.id, Handlers: handlers}, Pull: e.concurrency}); err != nil {
+		return err
+	}
+
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		ticker := time.NewTicker(e.heartbeat)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				if send(&ExecutorMessage{Heartbeat: true}) != nil {
+					return
+				}
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	for {
+		var m CoordinatorMessage
+		if err := stream.RecvMsg(&m); err != nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			return err
+		}
+		if m.Cancel != 0 {
+			withLock(&jobsMu, func() {
+				if cancel := jobs[m.Cancel]; cancel != nil {
+					cancel()
+				}
+			})
+		}
+		if job := m.Job; job != nil {
+			jobCtx, jobCancel := context.WithCancel(ctx)
+			withLock(&jobsMu, func() {
+				jobs[job.ID] = jobCancel
+			})
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				r := e.run(jobCtx, job)
+				withLock(&jobsMu, func() {
+					delete(jobs, job.ID)
+				})
+				jobCancel()
+				if ctx.Err() != nil {
+					// canceled by shutdown, coordinator dispatches it again.
+					return
+				}
+				// pull next job along with the result.
+				send(&ExecutorMessage{Result: r, Pull: 1})
+			}()
+		}
+	}
+}
+
+func (e *Executor) run(ctx context.Context, job *Job) (r *Result) {
+	r = &Result{JobID: job.ID}
+	defer func() {
+		if err := recover(); err != nil {
+			r.Output, r.Error = nil, fmt.Sprintf("panic: %v", err)
+		}
+	}()
+	h := e.handlers[job.Handler]
+	if h == nil {
+		r.Error = fmt.Sprintf("qgrpc: no handler %q", job.Handler)
+		return r
+	}
+	output, err := h(ctx, job.Payload)
+	if err != nil {
+		r.Error = err.Error()
+		return r
+	}
+	if r.Output, err = json.Marshal(output); err != nil {
+		r.Error = err.Error()
+	}
+	return r
+}
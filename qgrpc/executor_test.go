@@ -0,0 +1,58 @@
+package qgrpc
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestExecutor_HeartbeatTimeout(t *testing.T) {
+	c := NewCoordinator(1).SetHeartbeatTimeout(time.Millisecond * 40)
+	defer c.Stop()
+	cc := serve(t, c)
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	err := NewExecutor("silent", 1).SetHeartbeatInterval(time.Hour).Run(ctx, cc)
+	if status.Code(err) != codes.DeadlineExceeded {
+		t.Errorf("except %v, actual %v", codes.DeadlineExceeded, err)
+	}
+
+	done := make(chan error)
+	go func() {
+		done <- NewExecutor("alive", 1).SetHeartbeatInterval(time.Millisecond * 10).Run(ctx, cc)
+	}()
+	select {
+	case err := <-done:
+		t.Errorf("except executor keeps connected, actual %v", err)
+	case <-time.After(time.Millisecond * 100):
+	}
+}
+
+func TestExecutor_run(t *testing.T) {
+	e := NewExecutor("a", 1).Handle("panic", func(ctx context.Context, payload json.RawMessage) (interface{}, error) {
+		panic("boom")
+	})
+	if r := e.run(context.Background(), &Job{ID: 1, Handler: "panic"}); r.Error != "panic: boom" {
+		t.Errorf("except panic, actual %+v", r)
+	}
+	if r := e.run(context.Background(), &Job{ID: 2, Handler: "missing"}); r.Error != `qgrpc: no handler "missing"` {
+		t.Errorf("except no handler, actual %+v", r)
+	}
+}
+
+func TestHeartbeat_Invalid(t *testing.T) {
+	if d := NewExecutor("a", 1).SetHeartbeatInterval(0).heartbeat; d != DefaultHeartbeatInterval {
+		t.Errorf("except %v, actual %v", DefaultHeartbeatInterval, d)
+	}
+	if d := NewCoordinator(1).SetHeartbeatTimeout(-1).heartbeatTimeout; d != DefaultHeartbeatTimeout {
+		t.Errorf("except %v, actual %v", DefaultHeartbeatTimeout, d)
+	}
+	if d := NewCoordinator(1).SetHeartbeatTimeout(3).heartbeatTimeout; d != time.Millisecond {
+		t.Errorf("except %v, actual %v", time.Millisecond, d)
+	}
+}
@@ -0,0 +1,192 @@
+package qhttp
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ErrNotInflight is returned by Ack if message is acked already or redelivered.
+var ErrNotInflight = errors.New("qhttp: message not inflight")
+
+// StatusError is error response of server.
+type StatusError struct {
+	Method string
+	Code   int
+	Status string
+	Msg    string
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("qhttp: %s %s: %s", e.Method, e.Status, e.Msg)
+}
+
+// retryInterval is interval between retries of Enqueue and Dequeue.
+const retryInterval = time.Second
+
+// Client access a queue hosted by Server, it implements Enqueue and Dequeue like syncq2.SyncQueue.
+type Client struct {
+	url  string // url of the queue
+	hc   *http.Client
+	wait time.Duration
+}
+
+// NewClient create Client of queue name hosted by server at baseURL, hc nil means http.DefaultClient.
+func NewClient(baseURL, name string, hc *http.Client) *Client {
+	if hc == nil {
+		hc = http.DefaultClient
+	}
+	return &Client{
+		url:  strings.TrimRight(baseURL, "/") + "/queues/" + url.PathEscape(name),
+		hc:   hc,
+		wait: DefaultWait,
+	}
+}
+
+// SetWait set how long a dequeue request polls the server, hc must not timeout within it.
+func (c *Client) SetWait(wait time.Duration) *Client {
+	c.wait = wait
+	return c
+}
+
+// Enqueue put value encoded to JSON into queue, it retries on transport errors and 5xx responses until succeeded.
+// value is dropped if it can not be encoded or server rejects it, use EnqueueContext to handle errors.
+func (c *Client) Enqueue(value interface{}) {
+	for {
+		err := c.EnqueueContext(context.Background(), value)
+		if err == nil || !temporary(err) {
+			return
+		}
+		time.Sleep(retryInterval)
+	}
+}
+
+// Dequeue take message from queue and ack it, it blocks until message received and retries on transport errors
+// and 5xx responses. it returns nil if server rejects the request or the message can not be decoded.
+// JSON numbers are decoded to float64, use DequeueMessage to decode into specific type.
+func (c *Client) Dequeue() interface{} {
+	for {
+		v, err := c.DequeueContext(context.Background())
+		if err == nil || !temporary(err) {
+			return v
+		}
+		time.Sleep(retryInterval)
+	}
+}
+
+// temporary return whether operation failed with err may succeed on retry.
+// ErrNotInflight of ack means the message is redelivered, dequeue again takes it or another message.
+func temporary(err error) bool {
+	var (
+		ue *url.Error
+		se *StatusError
+	)
+	switch {
+	case errors.As(err, &se):
+		return se.Code >= http.StatusInternalServerError
+	case errors.As(err, &ue), err == ErrNotInflight:
+		return true
+	}
+	return false
+}
+
+// EnqueueContext put value encoded to JSON into queue.
+func (c *Client) EnqueueContext(ctx context.Context, value interface{}) error {
+	body, err := json.Marshal(value)
+	if err != nil {
+		return err
+	}
+	resp, err := c.do(ctx, http.MethodPost, c.url+"/messages", body)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	return nil
+}
+
+// DequeueContext take message from queue like Dequeue, it returns error if ctx done or request failed.
+func (c *Client) DequeueContext(ctx context.Context) (interface{}, error) {
+	msg, err := c.DequeueMessage(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if err := c.Ack(ctx, msg.ID); err != nil {
+		return nil, err
+	}
+	var v interface{}
+	err = json.Unmarshal(msg.Value, &v)
+	return v, err
+}
+
+// DequeueMessage take message from queue without ack, it blocks until message received or ctx done.
+// the message must be acked in ack timeout of server, otherwise it is redelivered.
+func (c *Client) DequeueMessage(ctx context.Context) (*Message, error) {
+	for {
+		resp, err := c.do(ctx, http.MethodGet, c.url+"/messages?wait="+c.wait.String(), nil)
+		if err != nil {
+			return nil, err
+		}
+		if resp.StatusCode == http.StatusNoContent {
+			resp.Body.Close()
+			continue // no message in wait, poll again
+		}
+		msg := &Message{}
+		err = json.NewDecoder(resp.Body).Decode(msg)
+		resp.Body.Close()
+		return msg, err
+	}
+}
+
+// Ack ack message delivered by DequeueMessage.
+func (c *Client) Ack(ctx context.Context, id uint64) error {
+	resp, err := c.do(ctx, http.MethodPost, c.url+"/messages/"+strconv.FormatUint(id, 10)+"/ack", nil)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	return nil
+}
+
+// Stats return statistics of queue.
+func (c *Client) Stats(ctx context.Context) (Stats, error) {
+	var stats Stats
+	resp, err := c.do(ctx, http.MethodGet, c.url+"/stats", nil)
+	if err != nil {
+		return stats, err
+	}
+	defer resp.Body.Close()
+	err = json.NewDecoder(resp.Body).Decode(&stats)
+	return stats, err
+}
+
+// do send request, responses with error status are converted to error.
+func (c *Client) do(ctx context.Context, method, u string, body []byte) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, method, u, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	resp, err := c.hc.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode < 300 {
+		return resp, nil
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusNotFound && strings.HasSuffix(u, "/ack") {
+		return nil, ErrNotInflight
+	}
+	msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+	return nil, &StatusError{Method: method, Code: resp.StatusCode, Status: resp.Status, Msg: string(bytes.TrimSpace(msg))}
+}
@@ -0,0 +1,110 @@
+package qhttp
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestClient(t *testing.T) {
+	s := NewServer(time.Second)
+	defer s.Close()
+	srv := httptest.NewServer(s)
+	defer srv.Close()
+
+	producer := NewClient(srv.URL, "jobs", srv.Client())
+	consumer := NewClient(srv.URL, "jobs", srv.Client()).SetWait(time.Millisecond * 20)
+
+	go func() {
+		time.Sleep(time.Millisecond * 50) // consumer polls more than once
+		producer.Enqueue("hello")
+		producer.Enqueue(2)
+	}()
+	if v := consumer.Dequeue(); v != "hello" {
+		t.Errorf("except hello, actual %v", v)
+	}
+	if v := consumer.Dequeue(); v != float64(2) {
+		t.Errorf("except 2, actual %v", v)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond*50)
+	defer cancel()
+	if _, err := consumer.DequeueContext(ctx); err == nil {
+		t.Error("except error when ctx done")
+	}
+
+	producer.Enqueue("manual")
+	msg, err := consumer.DequeueMessage(context.Background())
+	if err != nil || string(msg.Value) != `"manual"` {
+		t.Fatalf("except manual, actual %+v %v", msg, err)
+	}
+	if err := consumer.Ack(context.Background(), msg.ID); err != nil {
+		t.Error(err)
+	}
+	if err := consumer.Ack(context.Background(), msg.ID); err != ErrNotInflight {
+		t.Errorf("except %v, actual %v", ErrNotInflight, err)
+	}
+
+	stats, err := producer.Stats(context.Background())
+	if err != nil || stats.Enqueued != 3 || stats.Acked != 3 || stats.Inflight != 0 {
+		t.Errorf("stats not except, %+v %v", stats, err)
+	}
+}
+
+func TestClient_PermanentErrors(t *testing.T) {
+	s := NewServer(time.Second)
+	defer s.Close()
+	srv := httptest.NewServer(s)
+	defer srv.Close()
+	c := NewClient(srv.URL, "jobs", srv.Client())
+
+	// unencodable and rejected values are not retried.
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		c.Enqueue(make(chan int))
+		c.Enqueue(strings.Repeat("x", maxBody))
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("except Enqueue returned")
+	}
+
+	bad := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "bad request", http.StatusBadRequest)
+	}))
+	defer bad.Close()
+	dequeued := make(chan interface{})
+	go func() {
+		dequeued <- NewClient(bad.URL, "jobs", bad.Client()).Dequeue()
+	}()
+	select {
+	case v := <-dequeued:
+		if v != nil {
+			t.Errorf("except nil, actual %v", v)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("except Dequeue returned")
+	}
+}
+
+func TestTemporary(t *testing.T) {
+	for err, except := range map[error]bool{
+		&StatusError{Code: http.StatusServiceUnavailable}: true,
+		&StatusError{Code: http.StatusBadRequest}:         false,
+		&url.Error{Op: "Get", Err: io.ErrUnexpectedEOF}:   true,
+		ErrNotInflight:               true,
+		&json.UnsupportedTypeError{}: false,
+	} {
+		if actual := temporary(err); actual != except {
+			t.Errorf("%v: except %v, actual %v", err, except, actual)
+		}
+	}
+}
@@ -0,0 +1,252 @@
+package qhttp
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/luweimy/goutil/syncq2"
+)
+
+const (
+	// DefaultWait is the longest time a dequeue request polls if wait is not specified.
+	DefaultWait = time.Second * 30
+	// MaxWait limit wait of dequeue requests.
+	MaxWait = time.Minute * 5
+	// maxBody limit size of enqueued message.
+	maxBody = 1 << 20
+)
+
+// Message is a message delivered by dequeue, it must be acked in ack timeout of server if not auto acked.
+type Message struct {
+	ID    uint64          `json:"id"`
+	Value json.RawMessage `json:"value"`
+}
+
+// Stats is statistics of a queue.
+type Stats struct {
+	Len         int    `json:"len"`      // messages waiting in queue
+	Inflight    int    `json:"inflight"` // messages delivered but not acked
+	Enqueued    uint64 `json:"enqueued"`
+	Dequeued    uint64 `json:"dequeued"`
+	Acked       uint64 `json:"acked"`
+	Redelivered uint64 `json:"redelivered"`
+}
+
+// Server host named queues built on syncq2 over HTTP/JSON API, queues are created on first use.
+//
+//	POST /queues/{name}/messages            enqueue the JSON request body
+//	GET  /queues/{name}/messages?wait=10s   long-poll dequeue, 204 if no message in wait
+//	POST /queues/{name}/messages/{id}/ack   ack delivered message, 404 if not inflight
+//	GET  /queues/{name}/stats               statistics of the queue
+//
+// a delivered message not acked in ack timeout is enqueued again, so a message is lost
+// neither if the consumer crashed nor if the long-poll response failed.
+type Server struct {
+	ctx        context.Context
+	cancel     context.CancelFunc
+	ackTimeout time.Duration
+	mux        *http.ServeMux
+
+	mu     sync.Mutex
+	queues map[string]*queue
+}
+
+type queue struct {
+	q *syncq2.SyncQueue // of json.RawMessage
+
+	mu       sync.Mutex
+	seq      uint64
+	inflight map[uint64]*time.Timer // redelivery timers of messages not acked
+	stats    Stats
+}
+
+// NewServer create Server, messages not acked in ackTimeout are redelivered.
+// ackTimeout <= 0 ack messages once delivered.
+func NewServer(ackTimeout time.Duration) *Server {
+	ctx, cancel := context.WithCancel(context.Background())
+	s := &Server{
+		ctx:        ctx,
+		cancel:     cancel,
+		ackTimeout: ackTimeout,
+		mux:        http.NewServeMux(),
+		queues:     make(map[string]*queue),
+	}
+	s.mux.HandleFunc("POST /queues/{name}/messages", s.handleEnqueue)
+	s.mux.HandleFunc("GET /queues/{name}/messages", s.handleDequeue)
+	s.mux.HandleFunc("POST /queues/{name}/messages/{id}/ack", s.handleAck)
+	s.mux.HandleFunc("GET /queues/{name}/stats", s.handleStats)
+	return s
+}
+
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mux.ServeHTTP(w, r)
+}
+
+// Queue return queue of name to share messages with HTTP clients in-process.
+// elements enqueued over HTTP are json.RawMessage, elements enqueued in-process are encoded to JSON when dequeued over HTTP.
+func (s *Server) Queue(name string) *syncq2.SyncQueue {
+	return s.queue(name).q
+}
+
+// Stats return statistics of queue of name.
+func (s *Server) Stats(name string) Stats {
+	q := s.queue(name)
+	var stats Stats
+	withLock(&q.mu, func() {
+		stats = q.stats
+		stats.Inflight = len(q.inflight)
+	})
+	stats.Len = q.q.Len()
+	return stats
+}
+
+// Close destroy all queues and stop redelivery, pending dequeue requests return 503.
+func (s *Server) Close() {
+	s.cancel()
+	withLock(&s.mu, func() {
+		for _, q := range s.queues {
+			withLock(&q.mu, func() {
+				for _, timer := range q.inflight {
+					timer.Stop()
+				}
+			})
+			q.q.Destroy()
+		}
+	})
+}
+
+func (s *Server) queue(name string) *queue {
+	var q *queue
+	withLock(&s.mu, func() {
+		if q = s.queues[name]; q == nil {
+			q = &queue{q: syncq2.New(), inflight: make(map[uint64]*time.Timer)}
+			s.queues[name] = q
+		}
+	})
+	return q
+}
+
+func (s *Server) handleEnqueue(w http.ResponseWriter, r *http.Request) {
+	body, err := io.ReadAll(io.LimitReader(r.Body, maxBody+1))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if len(body) > maxBody {
+		http.Error(w, "message too large", http.StatusRequestEntityTooLarge)
+		return
+	}
+	if !json.Valid(body) {
+		http.Error(w, "invalid JSON message", http.StatusBadRequest)
+		return
+	}
+	q := s.queue(r.PathValue("name"))
+	withLock(&q.mu, func() {
+		q.stats.Enqueued++
+	})
+	q.q.Enqueue(json.RawMessage(body))
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (s *Server) handleDequeue(w http.ResponseWriter, r *http.Request) {
+	wait := DefaultWait
+	if v := r.FormValue("wait"); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil || d < 0 {
+			http.Error(w, "invalid wait", http.StatusBadRequest)
+			return
+		}
+		wait = min(d, MaxWait)
+	}
+	ctx, cancel := context.WithTimeout(r.Context(), wait)
+	defer cancel()
+
+	q := s.queue(r.PathValue("name"))
+	v, err := q.q.DequeueContext(ctx)
+	switch {
+	case err == syncq2.ErrDestroyed:
+		http.Error(w, "server closed", http.StatusServiceUnavailable)
+		return
+	case err != nil:
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+	msg := Message{}
+	if raw, ok := v.(json.RawMessage); ok {
+		msg.Value = raw
+	} else if msg.Value, err = json.Marshal(v); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	withLock(&q.mu, func() {
+		q.seq++
+		msg.ID = q.seq
+		q.stats.Dequeued++
+		if s.ackTimeout > 0 && s.ctx.Err() == nil {
+			q.inflight[msg.ID] = time.AfterFunc(s.ackTimeout, func() {
+				s.redeliver(q, msg)
+			})
+		} else {
+			q.stats.Acked++
+		}
+	})
+	writeJSON(w, http.StatusOK, msg)
+}
+
+func (s *Server) redeliver(q *queue, msg Message) {
+	var redeliver bool
+	withLock(&q.mu, func() {
+		if _, ok := q.inflight[msg.ID]; ok {
+			delete(q.inflight, msg.ID)
+			q.stats.Redelivered++
+			redeliver = true
+		}
+	})
+	if redeliver && s.ctx.Err() == nil {
+		q.q.Enqueue(msg.Value)
+	}
+}
+
+func (s *Server) handleAck(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseUint(r.PathValue("id"), 10, 64)
+	if err != nil {
+		http.Error(w, "invalid message id", http.StatusBadRequest)
+		return
+	}
+	q := s.queue(r.PathValue("name"))
+	var ok bool
+	withLock(&q.mu, func() {
+		var timer *time.Timer
+		if timer, ok = q.inflight[id]; ok {
+			timer.Stop()
+			delete(q.inflight, id)
+			q.stats.Acked++
+		}
+	})
+	if !ok {
+		http.Error(w, "message not inflight", http.StatusNotFound)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (s *Server) handleStats(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, s.Stats(r.PathValue("name")))
+}
+
+func writeJSON(w http.ResponseWriter, code int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	json.NewEncoder(w).Encode(v)
+}
+
+func withLock(lk sync.Locker, fn func()) {
+	lk.Lock()
+	defer lk.Unlock() // in case fn panics
+	fn()
+}
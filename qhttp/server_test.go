@@ -0,0 +1,79 @@
+package qhttp
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestServer(t *testing.T) {
+	s := NewServer(time.Millisecond * 50)
+	defer s.Close()
+	srv := httptest.NewServer(s)
+	defer srv.Close()
+
+	post := func(path, body string) int {
+		resp, err := http.Post(srv.URL+path, "application/json", strings.NewReader(body))
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		return resp.StatusCode
+	}
+	dequeue := func() (int, Message) {
+		resp, err := http.Get(srv.URL + "/queues/jobs/messages?wait=20ms")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+		var msg Message
+		json.NewDecoder(resp.Body).Decode(&msg)
+		return resp.StatusCode, msg
+	}
+
+	if code := post("/queues/jobs/messages", `{"n":1}`); code != http.StatusNoContent {
+		t.Errorf("except 204, actual %d", code)
+	}
+	if code := post("/queues/jobs/messages", `{bad`); code != http.StatusBadRequest {
+		t.Errorf("except 400, actual %d", code)
+	}
+
+	code, msg := dequeue()
+	if code != http.StatusOK || string(msg.Value) != `{"n":1}` {
+		t.Errorf("except message, actual %d %+v", code, msg)
+	}
+	if code, _ := dequeue(); code != http.StatusNoContent {
+		t.Errorf("except 204 on empty queue, actual %d", code)
+	}
+
+	// not acked in time, the message is redelivered.
+	time.Sleep(time.Millisecond * 60)
+	code, msg = dequeue()
+	if code != http.StatusOK || string(msg.Value) != `{"n":1}` {
+		t.Errorf("except redelivered message, actual %d %+v", code, msg)
+	}
+	if code := post("/queues/jobs/messages/"+jsonID(msg.ID)+"/ack", ""); code != http.StatusNoContent {
+		t.Errorf("except 204, actual %d", code)
+	}
+	if code := post("/queues/jobs/messages/"+jsonID(msg.ID)+"/ack", ""); code != http.StatusNotFound {
+		t.Errorf("except 404 on acked message, actual %d", code)
+	}
+
+	s.Queue("jobs").Enqueue(map[string]int{"n": 2})
+	if code, msg := dequeue(); code != http.StatusOK || string(msg.Value) != `{"n":2}` {
+		t.Errorf("except in-process message, actual %d %+v", code, msg)
+	}
+
+	stats := s.Stats("jobs")
+	if stats.Enqueued != 1 || stats.Dequeued != 3 || stats.Acked != 1 || stats.Redelivered != 1 || stats.Inflight != 1 {
+		t.Errorf("stats not except, %+v", stats)
+	}
+}
+
+func jsonID(id uint64) string {
+	b, _ := json.Marshal(id)
+	return string(b)
+}
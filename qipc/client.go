@@ -0,0 +1,183 @@
+package qipc
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"net"
+	"sync"
+	"time"
+)
+
+// ErrClientClosed is returned by operations of closed Client.
+var ErrClientClosed = errors.New("qipc: client closed")
+
+// ServerError is error reported by server, retrying the operation does not help.
+type ServerError struct {
+	Msg string
+}
+
+func (e *ServerError) Error() string {
+	return e.Msg
+}
+
+// retryInterval is interval between retries of Enqueue and Dequeue.
+const retryInterval = time.Second
+
+// maxIdleConns limit num of idle connections kept by client.
+const maxIdleConns = 8
+
+// Client access queue served by Server over unix socket, Enqueue and Dequeue block like the local syncq.
+// it is safe for concurrent use, each pending operation holds a connection.
+type Client struct {
+	path string
+
+	mu     sync.Mutex
+	idle   []net.Conn
+	closed bool
+}
+
+// Dial create Client of server listening on unix socket path.
+func Dial(path string) (*Client, error) {
+	c := &Client{path: path}
+	conn, err := c.dial(context.Background())
+	if err != nil {
+		return nil, err
+	}
+	c.put(conn)
+	return c, nil
+}
+
+// Enqueue put value into queue, it blocks while queue is full and retries on connection errors until succeeded.
+// value is dropped if client closed, value too large or server reported error, use EnqueueContext to handle errors.
+func (c *Client) Enqueue(value []byte) {
+	for {
+		err := c.EnqueueContext(context.Background(), value)
+		if err == nil || !temporary(err) {
+			return
+		}
+		time.Sleep(retryInterval)
+	}
+}
+
+// Dequeue take value from queue, it blocks until queue is not empty and retries on connection errors,
+// it returns nil if client closed or server reported error.
+func (c *Client) Dequeue() []byte {
+	for {
+		v, err := c.DequeueContext(context.Background())
+		if err == nil || !temporary(err) {
+			return v
+		}
+		time.Sleep(retryInterval)
+	}
+}
+
+// temporary return whether operation failed with err may succeed on retry.
+func temporary(err error) bool {
+	var se *ServerError
+	return err != ErrClientClosed && err != errFrameTooLarge && !errors.As(err, &se)
+}
+
+// EnqueueContext put value into queue, it blocks while queue is full until ctx done.
+func (c *Client) EnqueueContext(ctx context.Context, value []byte) error {
+	_, err := c.do(ctx, opEnqueue, value)
+	return err
+}
+
+// DequeueContext take value from queue, it blocks while queue is empty until ctx done.
+// the value is not taken if ctx done in time, but may be lost if ctx done while it is replying.
+func (c *Client) DequeueContext(ctx context.Context) ([]byte, error) {
+	return c.do(ctx, opDequeue, nil)
+}
+
+// Len return num of elements in queue.
+func (c *Client) Len() (int, error) {
+	payload, err := c.do(context.Background(), opLen, nil)
+	if err != nil {
+		return 0, err
+	}
+	if len(payload) != 8 {
+		return 0, errors.New("qipc: invalid len reply")
+	}
+	return int(binary.BigEndian.Uint64(payload)), nil
+}
+
+// Close close idle connections, pending operations return error once they done.
+func (c *Client) Close() error {
+	withLock(&c.mu, func() {
+		c.closed = true
+		for _, conn := range c.idle {
+			conn.Close()
+		}
+		c.idle = nil
+	})
+	return nil
+}
+
+func (c *Client) do(ctx context.Context, op byte, payload []byte) ([]byte, error) {
+	conn, err := c.get(ctx)
+	if err != nil {
+		return nil, err
+	}
+	// close conn to abort the operation if ctx done, server gives up waiting the queue once conn closed.
+	stop := context.AfterFunc(ctx, func() {
+		conn.Close()
+	})
+	reply, payload, err := c.roundTrip(conn, op, payload)
+	if !stop() {
+		return nil, ctx.Err()
+	}
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	c.put(conn)
+	if reply == opError {
+		return nil, &ServerError{Msg: string(payload)}
+	}
+	return payload, nil
+}
+
+func (c *Client) roundTrip(conn net.Conn, op byte, payload []byte) (byte, []byte, error) {
+	if err := writeFrame(conn, op, payload); err != nil {
+		return 0, nil, err
+	}
+	return readFrame(conn)
+}
+
+func (c *Client) get(ctx context.Context) (net.Conn, error) {
+	var (
+		conn   net.Conn
+		closed bool
+	)
+	withLock(&c.mu, func() {
+		if closed = c.closed; !closed && len(c.idle) > 0 {
+			conn = c.idle[len(c.idle)-1]
+			c.idle = c.idle[:len(c.idle)-1]
+		}
+	})
+	if closed {
+		return nil, ErrClientClosed
+	}
+	if conn != nil {
+		return conn, nil
+	}
+	return c.dial(ctx)
+}
+
+func (c *Client) put(conn net.Conn) {
+	keep := false
+	withLock(&c.mu, func() {
+		if keep = !c.closed && len(c.idle) < maxIdleConns; keep {
+			c.idle = append(c.idle, conn)
+		}
+	})
+	if !keep {
+		conn.Close()
+	}
+}
+
+func (c *Client) dial(ctx context.Context) (net.Conn, error) {
+	var d net.Dialer
+	return d.DialContext(ctx, "unix", c.path)
+}
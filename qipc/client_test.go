@@ -0,0 +1,86 @@
+package qipc
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/luweimy/goutil/syncq"
+)
+
+func TestClient(t *testing.T) {
+	q := syncq.New()
+	_, path := serve(t, q)
+	c := dial(t, path)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			c.Enqueue([]byte("v"))
+		}()
+	}
+	wg.Wait()
+	if n, err := c.Len(); err != nil || n != 10 {
+		t.Errorf("except 10, actual %d %v", n, err)
+	}
+	for i := 0; i < 10; i++ {
+		if v := c.Dequeue(); string(v) != "v" {
+			t.Errorf("except v, actual %q", v)
+		}
+	}
+
+	q.Enqueue("s")
+	if v := c.Dequeue(); string(v) != "s" {
+		t.Errorf("except s, actual %q", v)
+	}
+	q.Enqueue(1)
+	var se *ServerError
+	if _, err := c.DequeueContext(context.Background()); !errors.As(err, &se) {
+		t.Errorf("except server error of element not []byte, actual %v", err)
+	}
+	// element is kept, Dequeue does not retry.
+	if v := c.Dequeue(); v != nil {
+		t.Errorf("except nil, actual %q", v)
+	}
+	if v := q.Dequeue(); v != 1 {
+		t.Errorf("except 1, actual %v", v)
+	}
+}
+
+func TestClient_Context(t *testing.T) {
+	q := syncq.New()
+	_, path := serve(t, q)
+	c := dial(t, path)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if _, err := c.DequeueContext(ctx); err != context.DeadlineExceeded {
+		t.Errorf("except %v, actual %v", context.DeadlineExceeded, err)
+	}
+	time.Sleep(50 * time.Millisecond)
+
+	q.Enqueue([]byte("a"))
+	time.Sleep(50 * time.Millisecond)
+	if q.Len() != 1 {
+		t.Errorf("except 1, actual %d", q.Len())
+	}
+	if v := c.Dequeue(); string(v) != "a" {
+		t.Errorf("except a, actual %q", v)
+	}
+}
+
+func TestClient_Close(t *testing.T) {
+	_, path := serve(t, syncq.New())
+	c := dial(t, path)
+	c.Close()
+	if err := c.EnqueueContext(context.Background(), []byte("a")); err != ErrClientClosed {
+		t.Errorf("except %v, actual %v", ErrClientClosed, err)
+	}
+	if v := c.Dequeue(); v != nil {
+		t.Errorf("except nil, actual %q", v)
+	}
+}
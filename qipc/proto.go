@@ -0,0 +1,50 @@
+package qipc
+
+import (
+	"encoding/binary"
+	"errors"
+	"io"
+)
+
+// frame is [4 bytes big endian length of op and payload][1 byte op][payload].
+const (
+	opEnqueue byte = 1 // payload is value
+	opDequeue byte = 2
+	opLen     byte = 3
+
+	opOK    byte = 0x81 // payload is value of dequeue, 8 bytes big endian length of len, empty otherwise
+	opError byte = 0x82 // payload is error message
+)
+
+// MaxValueSize limit size of a value.
+const MaxValueSize = 64 << 20
+
+var errFrameTooLarge = errors.New("qipc: frame too large")
+
+func writeFrame(w io.Writer, op byte, payload []byte) error {
+	if len(payload) > MaxValueSize {
+		return errFrameTooLarge
+	}
+	buf := make([]byte, 5+len(payload))
+	binary.BigEndian.PutUint32(buf, uint32(1+len(payload)))
+	buf[4] = op
+	copy(buf[5:], payload)
+	_, err := w.Write(buf)
+	return err
+}
+
+func readFrame(r io.Reader) (byte, []byte, error) {
+	var head [5]byte
+	if _, err := io.ReadFull(r, head[:]); err != nil {
+		return 0, nil, err
+	}
+	n := binary.BigEndian.Uint32(head[:4])
+	if n == 0 || n-1 > MaxValueSize {
+		return 0, nil, errFrameTooLarge
+	}
+	payload := make([]byte, n-1)
+	if _, err := io.ReadFull(r, payload); err != nil {
+		return 0, nil, err
+	}
+	return head[4], payload, nil
+}
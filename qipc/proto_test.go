@@ -0,0 +1,30 @@
+package qipc
+
+import (
+	"bytes"
+	"encoding/binary"
+	"testing"
+)
+
+func TestFrame(t *testing.T) {
+	buf := &bytes.Buffer{}
+	writeFrame(buf, opEnqueue, []byte("hello"))
+	writeFrame(buf, opDequeue, nil)
+	if op, payload, err := readFrame(buf); err != nil || op != opEnqueue || string(payload) != "hello" {
+		t.Errorf("except %d hello, actual %d %q %v", opEnqueue, op, payload, err)
+	}
+	if op, payload, err := readFrame(buf); err != nil || op != opDequeue || len(payload) != 0 {
+		t.Errorf("except %d, actual %d %q %v", opDequeue, op, payload, err)
+	}
+}
+
+func TestFrameTooLarge(t *testing.T) {
+	if err := writeFrame(&bytes.Buffer{}, opEnqueue, make([]byte, MaxValueSize+1)); err != errFrameTooLarge {
+		t.Errorf("except %v, actual %v", errFrameTooLarge, err)
+	}
+	var head [5]byte
+	binary.BigEndian.PutUint32(head[:], MaxValueSize+2)
+	if _, _, err := readFrame(bytes.NewReader(head[:])); err != errFrameTooLarge {
+		t.Errorf("except %v, actual %v", errFrameTooLarge, err)
+	}
+}
@@ -0,0 +1,214 @@
+package qipc
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/luweimy/goutil/syncq"
+)
+
+// ErrClosed is returned by Serve after Close called.
+var ErrClosed = errors.New("qipc: server closed")
+
+// Server serve a syncq.SyncQueue to processes on the host over unix socket.
+// enqueue of remote producer is replied after the value put into queue, so it blocks while a bounded queue is full,
+// like the local queue. elements of queue must be []byte or string, others are put back to the end of queue
+// and reported to client as ServerError.
+type Server struct {
+	q      *syncq.SyncQueue
+	ctx    context.Context
+	cancel context.CancelFunc
+
+	mu        sync.Mutex
+	listeners map[net.Listener]struct{}
+	conns     map[net.Conn]struct{}
+	wg        sync.WaitGroup
+}
+
+func NewServer(q *syncq.SyncQueue) *Server {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &Server{
+		q:         q,
+		ctx:       ctx,
+		cancel:    cancel,
+		listeners: make(map[net.Listener]struct{}),
+		conns:     make(map[net.Conn]struct{}),
+	}
+}
+
+// ListenAndServe listen on unix socket path and serve connections until Close called.
+// stale socket file left at path is removed.
+func (s *Server) ListenAndServe(path string) error {
+	if fi, err := os.Stat(path); err == nil && fi.Mode()&os.ModeSocket != 0 {
+		os.Remove(path)
+	}
+	l, err := net.Listen("unix", path)
+	if err != nil {
+		return err
+	}
+	return s.Serve(l)
+}
+
+// Serve accept connections on l until Close called, l is closed when Serve returns.
+func (s *Server) Serve(l net.Listener) error {
+	closed := false
+	withLock(&s.mu, func() {
+		if closed = s.ctx.Err() != nil; !closed {
+			s.listeners[l] = struct{}{}
+		}
+	})
+	if closed {
+		l.Close()
+		return ErrClosed
+	}
+	defer withLock(&s.mu, func() {
+		delete(s.listeners, l)
+	})
+	defer l.Close()
+
+	for {
+		conn, err := l.Accept()
+		if err != nil {
+			if s.ctx.Err() != nil {
+				return ErrClosed
+			}
+			return err
+		}
+		withLock(&s.mu, func() {
+			if closed = s.ctx.Err() != nil; !closed {
+				s.conns[conn] = struct{}{}
+				s.wg.Add(1)
+			}
+		})
+		if closed {
+			conn.Close()
+			return ErrClosed
+		}
+		go s.serveConn(conn)
+	}
+}
+
+// Close stop listeners and close connections, blocked operations of clients return error.
+// the queue is not destroyed.
+func (s *Server) Close() error {
+	s.cancel()
+	withLock(&s.mu, func() {
+		for l := range s.listeners {
+			l.Close()
+		}
+		for conn := range s.conns {
+			conn.Close()
+		}
+	})
+	s.wg.Wait()
+	return nil
+}
+
+func (s *Server) serveConn(conn net.Conn) {
+	defer s.wg.Done()
+	defer withLock(&s.mu, func() {
+		delete(s.conns, conn)
+	})
+	defer conn.Close()
+
+	for {
+		op, payload, err := readFrame(conn)
+		if err != nil {
+			return
+		}
+		switch op {
+		case opEnqueue:
+			closed, stop := watchClosed(conn)
+			select {
+			case s.q.EnqueueC() <- payload:
+				err = stop()
+			case <-closed:
+				return
+			}
+			if err != nil {
+				return
+			}
+			err = writeFrame(conn, opOK, nil)
+		case opDequeue:
+			// the element is taken only if client still waits, so it is not lost if client gone.
+			closed, stop := watchClosed(conn)
+			var v interface{}
+			select {
+			case v = <-s.q.DequeueC():
+				err = stop()
+			case <-closed:
+				return
+			}
+			if err != nil {
+				// client gone just now, put the element back.
+				select {
+				case s.q.EnqueueC() <- v:
+				case <-s.ctx.Done():
+				}
+				return
+			}
+			switch v := v.(type) {
+			case []byte:
+				err = writeFrame(conn, opOK, v)
+			case string:
+				err = writeFrame(conn, opOK, []byte(v))
+			default:
+				// not to drop the element, put it back to the end of queue.
+				select {
+				case s.q.EnqueueC() <- v:
+				case <-s.ctx.Done():
+				}
+				err = writeFrame(conn, opError, []byte(fmt.Sprintf("qipc: element of %T is not []byte", v)))
+			}
+		case opLen:
+			var buf [8]byte
+			binary.BigEndian.PutUint64(buf[:], uint64(s.q.Len()))
+			err = writeFrame(conn, opOK, buf[:])
+		default:
+			writeFrame(conn, opError, []byte(fmt.Sprintf("qipc: unknown op %d", op)))
+			return
+		}
+		if err != nil {
+			return
+		}
+	}
+}
+
+// watchClosed watch conn while waiting the queue, client sends nothing until replied,
+// so read returns only if conn closed. stop interrupt watching, it returns error if conn closed or client misbehaved.
+func watchClosed(conn net.Conn) (<-chan struct{}, func() error) {
+	closed := make(chan struct{})
+	result := make(chan error, 1)
+	go func() {
+		var b [1]byte
+		_, err := conn.Read(b[:])
+		var ne net.Error
+		if errors.As(err, &ne) && ne.Timeout() {
+			result <- nil // interrupted by stop
+			return
+		}
+		if err == nil {
+			err = errors.New("qipc: unexpected data while waiting")
+		}
+		result <- err
+		close(closed)
+	}()
+	return closed, func() error {
+		conn.SetReadDeadline(time.Now())
+		err := <-result
+		conn.SetReadDeadline(time.Time{})
+		return err
+	}
+}
+
+func withLock(lk sync.Locker, fn func()) {
+	lk.Lock()
+	defer lk.Unlock() // in case fn panics
+	fn()
+}
@@ -0,0 +1,105 @@
+package qipc
+
+import (
+	"net"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/luweimy/goutil/syncq"
+)
+
+func serve(t *testing.T, q *syncq.SyncQueue) (*Server, string) {
+	path := filepath.Join(t.TempDir(), "q.sock")
+	l, err := net.Listen("unix", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := NewServer(q)
+	go s.Serve(l)
+	t.Cleanup(func() {
+		s.Close()
+	})
+	return s, path
+}
+
+func dial(t *testing.T, path string) *Client {
+	c, err := Dial(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		c.Close()
+	})
+	return c
+}
+
+func TestServer_Backpressure(t *testing.T) {
+	q := syncq.NewWithSize(1)
+	_, path := serve(t, q)
+	c := dial(t, path)
+
+	c.Enqueue([]byte("1"))
+	done := make(chan struct{})
+	go func() {
+		c.Enqueue([]byte("2"))
+		close(done)
+	}()
+	select {
+	case <-done:
+		t.Fatal("except enqueue blocked while queue full")
+	case <-time.After(100 * time.Millisecond):
+	}
+	if v := q.Dequeue(); string(v.([]byte)) != "1" {
+		t.Errorf("except 1, actual %s", v)
+	}
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("except enqueue done after dequeue")
+	}
+	if v := q.Dequeue(); string(v.([]byte)) != "2" {
+		t.Errorf("except 2, actual %s", v)
+	}
+}
+
+func TestServer_ClientGone(t *testing.T) {
+	q := syncq.New()
+	_, path := serve(t, q)
+
+	conn, err := net.Dial("unix", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	writeFrame(conn, opDequeue, nil)
+	time.Sleep(50 * time.Millisecond)
+	conn.Close()
+	time.Sleep(50 * time.Millisecond)
+
+	q.Enqueue("a")
+	time.Sleep(50 * time.Millisecond)
+	if q.Len() != 1 {
+		t.Errorf("except 1, actual %d", q.Len())
+	}
+}
+
+func TestServer_Close(t *testing.T) {
+	s, path := serve(t, syncq.New())
+	c := dial(t, path)
+
+	errc := make(chan error, 1)
+	go func() {
+		_, err := c.DequeueContext(t.Context())
+		errc <- err
+	}()
+	time.Sleep(50 * time.Millisecond)
+	s.Close()
+	select {
+	case err := <-errc:
+		if err == nil {
+			t.Error("except error after server closed")
+		}
+	case <-time.After(time.Second):
+		t.Fatal("except dequeue returned after server closed")
+	}
+}
@@ -0,0 +1,48 @@
+package qresp
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestReadCommand(t *testing.T) {
+	r := bufio.NewReader(strings.NewReader("*3\r\n$5\r\nLPUSH\r\n$1\r\nk\r\n$4\r\na\r\nb\r\nLLEN k\r\n*1\r\n:3\r\n"))
+	for _, except := range [][]string{{"LPUSH", "k", "a\r\nb"}, {"LLEN", "k"}} {
+		if args, err := readCommand(r); err != nil || !reflect.DeepEqual(args, except) {
+			t.Errorf("except %q, actual %q %v", except, args, err)
+		}
+	}
+	if _, err := readCommand(r); err != errProtocol {
+		t.Errorf("except %v, actual %v", errProtocol, err)
+	}
+}
+
+func TestWriter(t *testing.T) {
+	buf := &bytes.Buffer{}
+	w := writer{bufio.NewWriter(buf)}
+	w.simple("OK")
+	w.error("bad %s", "cmd")
+	w.integer(3)
+	w.array("k", "v")
+	w.nullArray()
+	w.Flush()
+	if except := "+OK\r\n-ERR bad cmd\r\n:3\r\n*2\r\n$1\r\nk\r\n$1\r\nv\r\n*-1\r\n"; buf.String() != except {
+		t.Errorf("except %q, actual %q", except, buf.String())
+	}
+}
+
+func TestReadCommand_LargeBulk(t *testing.T) {
+	// header claims a huge bulk but no payload follows.
+	r := bufio.NewReader(strings.NewReader("*1\r\n$67108864\r\nab"))
+	if _, err := readCommand(r); err != io.ErrUnexpectedEOF {
+		t.Errorf("except %v, actual %v", io.ErrUnexpectedEOF, err)
+	}
+	r = bufio.NewReader(strings.NewReader("*1\r\n$67108865\r\n"))
+	if _, err := readCommand(r); err != errProtocol {
+		t.Errorf("except %v, actual %v", errProtocol, err)
+	}
+}
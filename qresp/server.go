@@ -0,0 +1,311 @@
+package qresp
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/luweimy/goutil/syncq2"
+)
+
+// ErrClosed is returned by Serve after Close called.
+var ErrClosed = errors.New("qresp: server closed")
+
+// Server expose syncq2 queues through a subset of RESP(Redis protocol) commands, so Redis clients
+// can use in-process queues as a lightweight local broker. each key maps to a queue created on first use:
+//
+//	PING [message]
+//	LPUSH key value [value ...]      put values to head of queue, reply length
+//	RPUSH key value [value ...]      put values to tail of queue, reply length
+//	BLPOP key [key ...] timeout      take from head of the first non-empty queue, timeout in seconds, 0 blocks forever
+//	BRPOP key [key ...] timeout      take from tail of the first non-empty queue
+//	LLEN key                         reply length of queue
+//	DEL key [key ...]                clear queues, reply num of non-empty queues cleared
+//	QUIT
+//
+// queues of keys are kept even though they are empty, like Redis an empty list does not exist.
+type Server struct {
+	ctx    context.Context
+	cancel context.CancelFunc
+
+	mu        sync.Mutex
+	queues    map[string]*syncq2.SyncQueue
+	listeners map[net.Listener]struct{}
+	conns     map[net.Conn]struct{}
+	wg        sync.WaitGroup
+}
+
+func NewServer() *Server {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &Server{
+		ctx:       ctx,
+		cancel:    cancel,
+		queues:    make(map[string]*syncq2.SyncQueue),
+		listeners: make(map[net.Listener]struct{}),
+		conns:     make(map[net.Conn]struct{}),
+	}
+}
+
+// Queue return queue of key to share elements with Redis clients in-process.
+// elements pushed by clients are string, other elements are formatted by fmt.Sprint when popped by clients.
+func (s *Server) Queue(key string) *syncq2.SyncQueue {
+	var q *syncq2.SyncQueue
+	withLock(&s.mu, func() {
+		if q = s.queues[key]; q == nil {
+			q = syncq2.New()
+			s.queues[key] = q
+		}
+	})
+	return q
+}
+
+// ListenAndServe listen on TCP address addr and serve connections until Close called.
+func (s *Server) ListenAndServe(addr string) error {
+	l, err := net.Listen("tcp", addr)
+	if err != nil {
+		return err
+	}
+	return s.Serve(l)
+}
+
+// Serve accept connections on l until Close called, l is closed when Serve returns.
+func (s *Server) Serve(l net.Listener) error {
+	closed := false
+	withLock(&s.mu, func() {
+		if closed = s.ctx.Err() != nil; !closed {
+			s.listeners[l] = struct{}{}
+		}
+	})
+	if closed {
+		l.Close()
+		return ErrClosed
+	}
+	defer withLock(&s.mu, func() {
+		delete(s.listeners, l)
+	})
+	defer l.Close()
+
+	for {
+		conn, err := l.Accept()
+		if err != nil {
+			if s.ctx.Err() != nil {
+				return ErrClosed
+			}
+			var ne net.Error
+			if errors.As(err, &ne) && ne.Timeout() {
+				time.Sleep(time.Millisecond * 10)
+				continue
+			}
+			return err
+		}
+		withLock(&s.mu, func() {
+			if closed = s.ctx.Err() != nil; !closed {
+				s.conns[conn] = struct{}{}
+				s.wg.Add(1)
+			}
+		})
+		if closed {
+			conn.Close()
+			return ErrClosed
+		}
+		go s.serveConn(conn)
+	}
+}
+
+// Close stop listeners and close connections, blocked pops return, then destroy queues.
+func (s *Server) Close() error {
+	s.cancel()
+	withLock(&s.mu, func() {
+		for l := range s.listeners {
+			l.Close()
+		}
+		for conn := range s.conns {
+			conn.Close()
+		}
+	})
+	s.wg.Wait()
+	withLock(&s.mu, func() {
+		for _, q := range s.queues {
+			q.Destroy()
+		}
+	})
+	return nil
+}
+
+func (s *Server) serveConn(conn net.Conn) {
+	defer s.wg.Done()
+	defer withLock(&s.mu, func() {
+		delete(s.conns, conn)
+	})
+	defer conn.Close()
+
+	r := bufio.NewReader(conn)
+	w := writer{bufio.NewWriter(conn)}
+	for {
+		args, err := readCommand(r)
+		if err != nil {
+			if err == errProtocol {
+				w.error("Protocol error")
+				w.Flush()
+			}
+			return
+		}
+		if len(args) == 0 {
+			continue
+		}
+		quit := s.exec(w, args)
+		if err := w.Flush(); err != nil || quit {
+			return
+		}
+	}
+}
+
+// exec execute command args and write reply, return true if connection should be closed.
+func (s *Server) exec(w writer, args []string) bool {
+	cmd := strings.ToUpper(args[0])
+	arity := func(n int) bool {
+		if len(args) < n {
+			w.error("wrong number of arguments for '%s' command", strings.ToLower(cmd))
+			return false
+		}
+		return true
+	}
+	switch cmd {
+	case "PING":
+		if len(args) > 1 {
+			w.bulk(args[1])
+		} else {
+			w.simple("PONG")
+		}
+	case "QUIT":
+		w.simple("OK")
+		return true
+	case "LPUSH", "RPUSH":
+		if !arity(3) {
+			break
+		}
+		q := s.Queue(args[1])
+		for _, v := range args[2:] {
+			if cmd == "LPUSH" {
+				q.EnqueueFront(v)
+			} else {
+				q.Enqueue(v)
+			}
+		}
+		w.integer(q.Len())
+	case "BLPOP", "BRPOP":
+		if !arity(3) {
+			break
+		}
+		timeout, err := strconv.ParseFloat(args[len(args)-1], 64)
+		if err != nil || timeout < 0 {
+			w.error("timeout is not a float or out of range")
+			break
+		}
+		s.blockingPop(w, args[1:len(args)-1], cmd == "BRPOP", time.Duration(timeout*float64(time.Second)))
+	case "LLEN":
+		if !arity(2) {
+			break
+		}
+		n := 0
+		withLock(&s.mu, func() {
+			if q := s.queues[args[1]]; q != nil {
+				n = q.Len()
+			}
+		})
+		w.integer(n)
+	case "DEL":
+		if !arity(2) {
+			break
+		}
+		n := 0
+		for _, key := range args[1:] {
+			var q *syncq2.SyncQueue
+			withLock(&s.mu, func() {
+				q = s.queues[key]
+			})
+			if q != nil && q.Clear() > 0 {
+				n++
+			}
+		}
+		w.integer(n)
+	default:
+		w.error("unknown command '%s'", args[0])
+	}
+	return false
+}
+
+// blockingPop take element from the first non-empty queue of keys, block until timeout if all empty.
+// the element is put back if reply failed to send.
+func (s *Server) blockingPop(w writer, keys []string, back bool, timeout time.Duration) {
+	ctx := s.ctx
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+
+	// subscribe before trying, so elements enqueued after trying are not missed.
+	queues := make([]*syncq2.SyncQueue, len(keys))
+	cases := make([]reflect.SelectCase, 0, len(keys)+1)
+	cases = append(cases, reflect.SelectCase{Dir: reflect.SelectRecv, Chan: reflect.ValueOf(ctx.Done())})
+	for i, key := range keys {
+		queues[i] = s.Queue(key)
+		ch, unsubscribe := queues[i].SubscribeEmpty()
+		defer unsubscribe()
+		cases = append(cases, reflect.SelectCase{Dir: reflect.SelectRecv, Chan: reflect.ValueOf(ch)})
+	}
+
+	for {
+		for i, q := range queues {
+			var (
+				v  interface{}
+				ok bool
+			)
+			if back {
+				v, ok = q.TryDequeueBack()
+			} else {
+				v, ok = q.TryDequeue()
+			}
+			if !ok {
+				continue
+			}
+			w.array(keys[i], toString(v))
+			if err := w.Flush(); err != nil {
+				if back {
+					q.Enqueue(v)
+				} else {
+					q.EnqueueFront(v)
+				}
+			}
+			return
+		}
+		if chosen, _, _ := reflect.Select(cases); chosen == 0 {
+			w.nullArray()
+			return
+		}
+	}
+}
+
+func toString(v interface{}) string {
+	switch v := v.(type) {
+	case string:
+		return v
+	case []byte:
+		return string(v)
+	}
+	return fmt.Sprint(v)
+}
+
+func withLock(lk sync.Locker, fn func()) {
+	lk.Lock()
+	defer lk.Unlock() // in case fn panics
+	fn()
+}
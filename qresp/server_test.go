@@ -0,0 +1,127 @@
+package qresp
+
+import (
+	"bufio"
+	"net"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+// client send commands as RESP arrays and read raw replies.
+type client struct {
+	t    *testing.T
+	conn net.Conn
+	r    *bufio.Reader
+}
+
+func dial(t *testing.T, addr string) *client {
+	conn, err := net.Dial("tcp", addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &client{t: t, conn: conn, r: bufio.NewReader(conn)}
+}
+
+// do send command and return reply with lines joined by space.
+func (c *client) do(args ...string) string {
+	var b strings.Builder
+	b.WriteString("*" + strconv.Itoa(len(args)) + "\r\n")
+	for _, arg := range args {
+		b.WriteString("$" + strconv.Itoa(len(arg)) + "\r\n" + arg + "\r\n")
+	}
+	if _, err := c.conn.Write([]byte(b.String())); err != nil {
+		c.t.Fatal(err)
+	}
+	return c.reply()
+}
+
+func (c *client) reply() string {
+	line, err := c.r.ReadString('\n')
+	if err != nil {
+		c.t.Fatal(err)
+	}
+	line = strings.TrimRight(line, "\r\n")
+	switch line[0] {
+	case '$':
+		n, _ := strconv.Atoi(line[1:])
+		if n < 0 {
+			return line
+		}
+		s, _ := c.r.ReadString('\n')
+		return strings.TrimRight(s, "\r\n")
+	case '*':
+		n, _ := strconv.Atoi(line[1:])
+		items := []string{line}
+		for i := 0; i < n; i++ {
+			items = append(items, c.reply())
+		}
+		return strings.Join(items, " ")
+	}
+	return line
+}
+
+func TestServer(t *testing.T) {
+	s := NewServer()
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	served := make(chan error, 1)
+	go func() {
+		served <- s.Serve(l)
+	}()
+
+	c := dial(t, l.Addr().String())
+	for _, step := range []struct {
+		args  []string
+		reply string
+	}{
+		{[]string{"PING"}, "+PONG"},
+		{[]string{"RPUSH", "jobs", "b", "c"}, ":2"},
+		{[]string{"LPUSH", "jobs", "a"}, ":3"},
+		{[]string{"LLEN", "jobs"}, ":3"},
+		{[]string{"BLPOP", "empty", "jobs", "0"}, "*2 jobs a"},
+		{[]string{"BRPOP", "jobs", "0"}, "*2 jobs c"},
+		{[]string{"BLPOP", "empty", "0.05"}, "*-1"},
+		{[]string{"DEL", "jobs", "empty", "missing"}, ":1"},
+		{[]string{"LLEN", "jobs"}, ":0"},
+		{[]string{"LLEN"}, "-ERR wrong number of arguments for 'llen' command"},
+		{[]string{"BLPOP", "jobs", "x"}, "-ERR timeout is not a float or out of range"},
+		{[]string{"GET", "jobs"}, "-ERR unknown command 'GET'"},
+	} {
+		if reply := c.do(step.args...); reply != step.reply {
+			t.Errorf("%q: except %q, actual %q", step.args, step.reply, reply)
+		}
+	}
+
+	// blocked pop is woken up by push from another client and in-process.
+	popped := make(chan string)
+	go func() {
+		popped <- c.do("BLPOP", "a", "b", "0")
+	}()
+	time.Sleep(time.Millisecond * 20)
+	if reply := dial(t, l.Addr().String()).do("RPUSH", "b", "x"); reply != ":1" {
+		t.Errorf("except :1, actual %q", reply)
+	}
+	if reply := <-popped; reply != "*2 b x" {
+		t.Errorf("except b x, actual %q", reply)
+	}
+	go func() {
+		popped <- c.do("BRPOP", "a", "1")
+	}()
+	time.Sleep(time.Millisecond * 20)
+	s.Queue("a").Enqueue(1)
+	if reply := <-popped; reply != "*2 a 1" {
+		t.Errorf("except a 1, actual %q", reply)
+	}
+
+	if reply := c.do("QUIT"); reply != "+OK" {
+		t.Errorf("except +OK, actual %q", reply)
+	}
+	s.Close()
+	if err := <-served; err != ErrClosed {
+		t.Errorf("except %v, actual %v", ErrClosed, err)
+	}
+}
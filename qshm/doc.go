@@ -0,0 +1,5 @@
+// Package qshm implement a byte queue in a mmap'd file shared by processes on the same host.
+// records are framed in a ring buffer and copied once on each side, DequeueFunc reads them in place.
+// waiting processes poll the ring briefly and then sleep on a named pipe, which is written only if
+// someone is waiting. it is available on linux only.
+package qshm
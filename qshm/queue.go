@@ -0,0 +1,320 @@
+//go:build linux
+
+package qshm
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"os"
+	"runtime"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"golang.org/x/sys/unix"
+)
+
+var (
+	// ErrClosed is returned by operations of closed Queue.
+	ErrClosed = errors.New("qshm: queue closed")
+	// ErrTooLarge is returned if record is larger than half of ring or its length does not fit in 32 bits.
+	ErrTooLarge = errors.New("qshm: record too large")
+)
+
+const (
+	// spin is num of times to check ring before sleeping.
+	spin = 64
+	// pollInterval bound time of sleeping, in case notification taken by another waiter of MPMC queue.
+	pollInterval = 10 * time.Millisecond
+)
+
+// Queue is a byte queue in file shared by processes, each process open the file by Open.
+// Enqueue blocks while ring is full, Dequeue blocks while ring is empty, like syncq.
+// queue created by Create allows one producer and one consumer at the same time,
+// queue created by CreateMPMC allows any num of them and exclude them by file locks.
+// notifications are sent through named pipes of path+".rd" and path+".wr".
+type Queue struct {
+	f        *os.File
+	data     []byte // mapping of file
+	ring     []byte
+	cap      uint64
+	mpmc     bool
+	readable *os.File // written if records enqueued
+	writable *os.File // written if records dequeued
+
+	producer sync.Mutex // exclude goroutines since file locks belong to open file description
+	consumer sync.Mutex
+	life     sync.RWMutex // held by operations to keep mapping until Close
+	closed   atomic.Bool
+}
+
+// Create create queue of file at path with ring of size bytes for a producer and a consumer, existing queue is overwritten.
+// size is rounded up to multiple of 8, a record can not be larger than half of it.
+func Create(path string, size int) (*Queue, error) {
+	return create(path, size, 0)
+}
+
+// CreateMPMC is same as Create, but the queue allows multiple producers and consumers.
+func CreateMPMC(path string, size int) (*Queue, error) {
+	return create(path, size, flagMPMC)
+}
+
+func create(path string, size int, flags uint64) (*Queue, error) {
+	if size < 64 {
+		return nil, fmt.Errorf("qshm: size %d less than 64", size)
+	}
+	capacity := frameSize(size - frameHead)
+	for _, p := range []string{path + ".rd", path + ".wr"} {
+		os.Remove(p)
+		if err := unix.Mkfifo(p, 0o600); err != nil {
+			return nil, &os.PathError{Op: "mkfifo", Path: p, Err: err}
+		}
+	}
+	// write header to a temporary file, so processes never open a file without header.
+	tmp := path + ".tmp"
+	f, err := os.OpenFile(tmp, os.O_RDWR|os.O_CREATE|os.O_TRUNC, 0o600)
+	if err != nil {
+		return nil, err
+	}
+	defer os.Remove(tmp)
+	defer f.Close()
+	var header [headerSize]byte
+	binary.LittleEndian.PutUint64(header[offMagic:], magic)
+	binary.LittleEndian.PutUint64(header[offCap:], capacity)
+	binary.LittleEndian.PutUint64(header[offFlags:], flags)
+	if _, err := f.Write(header[:]); err != nil {
+		return nil, err
+	}
+	if err := f.Truncate(int64(headerSize + capacity)); err != nil {
+		return nil, err
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		return nil, err
+	}
+	return Open(path)
+}
+
+// Open open queue created at path.
+func Open(path string) (*Queue, error) {
+	f, err := os.OpenFile(path, os.O_RDWR, 0)
+	if err != nil {
+		return nil, err
+	}
+	q := &Queue{f: f}
+	if err := q.open(path); err != nil {
+		q.release()
+		return nil, err
+	}
+	return q, nil
+}
+
+func (q *Queue) open(path string) error {
+	fi, err := q.f.Stat()
+	if err != nil {
+		return err
+	}
+	if fi.Size() < headerSize {
+		return fmt.Errorf("qshm: %s is not a queue", path)
+	}
+	if q.data, err = unix.Mmap(int(q.f.Fd()), 0, int(fi.Size()), unix.PROT_READ|unix.PROT_WRITE, unix.MAP_SHARED); err != nil {
+		return &os.PathError{Op: "mmap", Path: path, Err: err}
+	}
+	q.cap = binary.LittleEndian.Uint64(q.data[offCap:])
+	if binary.LittleEndian.Uint64(q.data[offMagic:]) != magic || q.cap != uint64(fi.Size())-headerSize {
+		return fmt.Errorf("qshm: %s is not a queue", path)
+	}
+	q.ring = q.data[headerSize:]
+	q.mpmc = binary.LittleEndian.Uint64(q.data[offFlags:])&flagMPMC != 0
+	// open pipes for both reading and writing, so opening never blocks and reading never sees EOF.
+	if q.readable, err = os.OpenFile(path+".rd", os.O_RDWR|unix.O_NONBLOCK, 0); err != nil {
+		return err
+	}
+	if q.writable, err = os.OpenFile(path+".wr", os.O_RDWR|unix.O_NONBLOCK, 0); err != nil {
+		return err
+	}
+	return nil
+}
+
+// Remove remove files of queue at path, processes have opened it can still use it.
+func Remove(path string) error {
+	err := os.Remove(path)
+	os.Remove(path + ".rd")
+	os.Remove(path + ".wr")
+	return err
+}
+
+// Enqueue put value into queue, it blocks until there is space for it.
+func (q *Queue) Enqueue(value []byte) error {
+	return q.EnqueueContext(context.Background(), value)
+}
+
+// EnqueueContext put value into queue, it blocks until there is space for it or ctx done.
+func (q *Queue) EnqueueContext(ctx context.Context, value []byte) error {
+	if !q.fits(len(value)) {
+		return ErrTooLarge
+	}
+	size := frameSize(len(value))
+	return q.do(ctx, func() bool {
+		return q.tryEnqueue(value)
+	}, q.writable, offWriteWaiters, func() bool {
+		return q.hasSpace(size)
+	})
+}
+
+// TryEnqueue put value into queue if there is space for it.
+func (q *Queue) TryEnqueue(value []byte) (bool, error) {
+	if !q.fits(len(value)) {
+		return false, ErrTooLarge
+	}
+	q.life.RLock()
+	defer q.life.RUnlock()
+	if q.closed.Load() {
+		return false, ErrClosed
+	}
+	return q.tryEnqueue(value), nil
+}
+
+// fits return whether record of n bytes can be framed in ring, length of record is 32 bits and wrapMark is reserved.
+func (q *Queue) fits(n int) bool {
+	return uint64(n) < wrapMark && frameSize(n) <= q.cap/2
+}
+
+// Dequeue take a record from queue, it blocks until queue is not empty.
+func (q *Queue) Dequeue() ([]byte, error) {
+	return q.DequeueContext(context.Background())
+}
+
+// DequeueContext take a record from queue, it blocks until queue is not empty or ctx done.
+func (q *Queue) DequeueContext(ctx context.Context) ([]byte, error) {
+	var v []byte
+	err := q.DequeueFunc(ctx, func(b []byte) {
+		v = append([]byte(nil), b...)
+	})
+	return v, err
+}
+
+// DequeueFunc take a record from queue and pass it to fn without copying, it blocks until queue is not empty or ctx done.
+// b refers to the shared memory and is valid only until fn returns, the record may be overwritten after that.
+// other consumers of MPMC queue are blocked while fn running.
+func (q *Queue) DequeueFunc(ctx context.Context, fn func(b []byte)) error {
+	return q.do(ctx, func() bool {
+		return q.tryDequeue(fn)
+	}, q.readable, offReadWaiters, q.hasRecords)
+}
+
+// TryDequeue take a record from queue if it is not empty.
+func (q *Queue) TryDequeue() ([]byte, bool) {
+	q.life.RLock()
+	defer q.life.RUnlock()
+	if q.closed.Load() {
+		return nil, false
+	}
+	var v []byte
+	ok := q.tryDequeue(func(b []byte) {
+		v = append([]byte(nil), b...)
+	})
+	return v, ok
+}
+
+// Len return num of records in queue.
+func (q *Queue) Len() int {
+	q.life.RLock()
+	defer q.life.RUnlock()
+	if q.closed.Load() {
+		return 0
+	}
+	return int(atomic.LoadInt64(q.i64(offCount)))
+}
+
+// Cap return size of ring in bytes.
+func (q *Queue) Cap() int {
+	return int(q.cap)
+}
+
+// Close unmap the file, blocked operations return ErrClosed. records are kept in the file.
+func (q *Queue) Close() error {
+	if q.closed.Swap(true) {
+		return nil
+	}
+	// wake waiters of this process.
+	q.readable.SetReadDeadline(time.Now())
+	q.writable.SetReadDeadline(time.Now())
+	q.life.Lock()
+	defer q.life.Unlock()
+	return q.release()
+}
+
+func (q *Queue) release() error {
+	var err error
+	if q.data != nil {
+		err = unix.Munmap(q.data)
+		q.data, q.ring = nil, nil
+	}
+	for _, f := range []*os.File{q.readable, q.writable, q.f} {
+		if f != nil {
+			f.Close()
+		}
+	}
+	return err
+}
+
+// do run try until it succeeded, wait on pipe while ready returns false.
+func (q *Queue) do(ctx context.Context, try func() bool, pipe *os.File, waiters int, ready func() bool) error {
+	q.life.RLock()
+	defer q.life.RUnlock()
+	for {
+		if q.closed.Load() {
+			return ErrClosed
+		}
+		if try() {
+			return nil
+		}
+		if err := q.wait(ctx, pipe, waiters, ready); err != nil {
+			return err
+		}
+	}
+}
+
+// wait until ready returns true. it spins for a while, then registers itself as waiter and sleeps on pipe.
+// the other side notifies after it updated ring and found waiters, since ready is checked after registered,
+// the notification is not missed.
+func (q *Queue) wait(ctx context.Context, pipe *os.File, waiters int, ready func() bool) error {
+	for i := 0; i < spin; i++ {
+		if ready() {
+			return nil
+		}
+		runtime.Gosched()
+	}
+	atomic.AddInt64(q.i64(waiters), 1)
+	defer atomic.AddInt64(q.i64(waiters), -1)
+	var buf [64]byte
+	for !ready() {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if q.closed.Load() {
+			return ErrClosed
+		}
+		deadline := time.Now().Add(pollInterval)
+		if d, ok := ctx.Deadline(); ok && d.Before(deadline) {
+			deadline = d
+		}
+		pipe.SetReadDeadline(deadline)
+		pipe.Read(buf[:]) // drain notifications
+	}
+	return nil
+}
+
+// notify write a byte to pipe without blocking, it is dropped if pipe is full since waiters will be waked anyway.
+func notify(pipe *os.File) {
+	rc, err := pipe.SyscallConn()
+	if err != nil {
+		return
+	}
+	rc.Write(func(fd uintptr) bool {
+		unix.Write(int(fd), []byte{1})
+		return true
+	})
+}
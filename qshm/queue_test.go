@@ -0,0 +1,200 @@
+//go:build linux
+
+package qshm
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+)
+
+func open(t *testing.T, path string) *Queue {
+	q, err := Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		q.Close()
+	})
+	return q
+}
+
+func TestQueue_Blocking(t *testing.T) {
+	producer, path := newQueue(t, 64, false)
+	consumer := open(t, path)
+
+	for i := 0; i < 4; i++ {
+		if err := producer.Enqueue([]byte("1234567")); err != nil {
+			t.Fatal(err)
+		}
+	}
+	done := make(chan error)
+	go func() {
+		done <- producer.Enqueue([]byte("full"))
+	}()
+	select {
+	case <-done:
+		t.Fatal("except enqueue blocked while queue full")
+	case <-time.After(50 * time.Millisecond):
+	}
+	if v, err := consumer.Dequeue(); err != nil || string(v) != "1234567" {
+		t.Errorf("except 1234567, actual %q %v", v, err)
+	}
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Error(err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("except enqueue done after dequeue")
+	}
+	if consumer.Len() != 4 {
+		t.Errorf("except 4, actual %d", consumer.Len())
+	}
+}
+
+func TestQueue_Wait(t *testing.T) {
+	producer, path := newQueue(t, 1024, false)
+	consumer := open(t, path)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if _, err := consumer.DequeueContext(ctx); err != context.DeadlineExceeded {
+		t.Errorf("except %v, actual %v", context.DeadlineExceeded, err)
+	}
+
+	go func() {
+		time.Sleep(50 * time.Millisecond)
+		producer.Enqueue([]byte("a"))
+	}()
+	err := consumer.DequeueFunc(context.Background(), func(b []byte) {
+		if string(b) != "a" {
+			t.Errorf("except a, actual %q", b)
+		}
+	})
+	if err != nil {
+		t.Error(err)
+	}
+}
+
+func TestQueue_Close(t *testing.T) {
+	q, _ := newQueue(t, 1024, false)
+	done := make(chan error)
+	go func() {
+		_, err := q.Dequeue()
+		done <- err
+	}()
+	time.Sleep(20 * time.Millisecond)
+	q.Close()
+	select {
+	case err := <-done:
+		if err != ErrClosed {
+			t.Errorf("except %v, actual %v", ErrClosed, err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("except dequeue returned after close")
+	}
+	if err := q.Enqueue([]byte("a")); err != ErrClosed {
+		t.Errorf("except %v, actual %v", ErrClosed, err)
+	}
+}
+
+func TestQueue_MPMC(t *testing.T) {
+	_, path := newQueue(t, 512, true)
+	const producers, consumers, n = 4, 4, 500
+
+	var wg sync.WaitGroup
+	for p := 0; p < producers; p++ {
+		q := open(t, path)
+		wg.Add(1)
+		go func(p int) {
+			defer wg.Done()
+			for i := 0; i < n; i++ {
+				q.Enqueue([]byte(fmt.Sprintf("%d-%d", p, i)))
+			}
+		}(p)
+	}
+	var (
+		mu   sync.Mutex
+		seen = make(map[string]bool)
+		cwg  sync.WaitGroup
+	)
+	ctx, cancel := context.WithCancel(context.Background())
+	for c := 0; c < consumers; c++ {
+		q := open(t, path)
+		cwg.Add(1)
+		go func() {
+			defer cwg.Done()
+			for {
+				v, err := q.DequeueContext(ctx)
+				if err != nil {
+					return
+				}
+				mu.Lock()
+				seen[string(v)] = true
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+	q := open(t, path)
+	for start := time.Now(); time.Since(start) < 5*time.Second; time.Sleep(time.Millisecond) {
+		if q.Len() == 0 {
+			break
+		}
+	}
+	cancel()
+	cwg.Wait()
+	if len(seen) != producers*n {
+		t.Errorf("except %d, actual %d", producers*n, len(seen))
+	}
+}
+
+func TestOpen(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "q")
+	os.WriteFile(path, []byte("not a queue"), 0o600)
+	if _, err := Open(path); err == nil {
+		t.Error("except error of opening non queue")
+	}
+	if _, err := Create(path, 8); err == nil {
+		t.Error("except error of size less than 64")
+	}
+}
+
+// TestQueue_Process transfer records to a child process running TestHelperProcess.
+func TestQueue_Process(t *testing.T) {
+	q, path := newQueue(t, 4096, false)
+	cmd := exec.Command(os.Args[0], "-test.run=^TestHelperProcess$")
+	cmd.Env = append(os.Environ(), "QSHM_HELPER="+path)
+	if err := cmd.Start(); err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 10000; i++ {
+		q.Enqueue([]byte(strconv.Itoa(i)))
+	}
+	if err := cmd.Wait(); err != nil {
+		t.Fatal(err)
+	}
+	if q.Len() != 0 {
+		t.Errorf("except 0, actual %d", q.Len())
+	}
+}
+
+func TestHelperProcess(t *testing.T) {
+	path := os.Getenv("QSHM_HELPER")
+	if path == "" {
+		t.Skip("run by TestQueue_Process")
+	}
+	q := open(t, path)
+	for i := 0; i < 10000; i++ {
+		if v, err := q.Dequeue(); err != nil || string(v) != strconv.Itoa(i) {
+			t.Fatalf("except %d, actual %q %v", i, v, err)
+		}
+	}
+}
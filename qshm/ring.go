@@ -0,0 +1,136 @@
+//go:build linux
+
+package qshm
+
+import (
+	"encoding/binary"
+	"sync/atomic"
+	"unsafe"
+
+	"golang.org/x/sys/unix"
+)
+
+// layout of file, counters are in separate cache lines since producers and consumers update them concurrently.
+const (
+	offMagic        = 0   // uint64
+	offCap          = 8   // uint64, size of ring
+	offFlags        = 16  // uint64
+	offHead         = 64  // uint64, bytes written ever, updated by producers
+	offTail         = 128 // uint64, bytes read ever, updated by consumers
+	offCount        = 192 // int64, num of records
+	offReadWaiters  = 256 // int64, num of consumers waiting for records
+	offWriteWaiters = 320 // int64, num of producers waiting for space
+	headerSize      = 4096
+
+	magic     = 0x3176716d68737100 // "\x00qshmqv1"
+	flagMPMC  = 1
+	wrapMark  = 0xffffffff // length of record tells consumer to continue at start of ring
+	frameHead = 4          // little endian length of record
+	align     = 8
+)
+
+// frameSize return size of record of n bytes in ring.
+func frameSize(n int) uint64 {
+	return uint64(frameHead+n+align-1) &^ (align - 1)
+}
+
+func (q *Queue) u64(off int) *uint64 {
+	return (*uint64)(unsafe.Pointer(&q.data[off]))
+}
+
+func (q *Queue) i64(off int) *int64 {
+	return (*int64)(unsafe.Pointer(&q.data[off]))
+}
+
+// space return whether a record of size fits into ring, and padding skipped before it at the end of ring.
+func (q *Queue) space(head, tail, size uint64) (uint64, bool) {
+	var pad uint64
+	if rest := q.cap - head%q.cap; rest < size {
+		pad = rest
+	}
+	return pad, head+pad+size-tail <= q.cap
+}
+
+func (q *Queue) tryEnqueue(value []byte) bool {
+	size := frameSize(len(value))
+	q.lock(&q.producer, 0)
+	defer q.unlock(&q.producer, 0)
+
+	head := atomic.LoadUint64(q.u64(offHead))
+	pad, ok := q.space(head, atomic.LoadUint64(q.u64(offTail)), size)
+	if !ok {
+		return false
+	}
+	if pad > 0 {
+		binary.LittleEndian.PutUint32(q.ring[head%q.cap:], wrapMark)
+		head += pad
+	}
+	off := head % q.cap
+	binary.LittleEndian.PutUint32(q.ring[off:], uint32(len(value)))
+	copy(q.ring[off+frameHead:], value)
+	// publish the record after it is written.
+	atomic.StoreUint64(q.u64(offHead), head+size)
+	atomic.AddInt64(q.i64(offCount), 1)
+	if atomic.LoadInt64(q.i64(offReadWaiters)) > 0 {
+		notify(q.readable)
+	}
+	return true
+}
+
+// tryDequeue pass the first record to fn and remove it, the record is valid only until fn returns.
+func (q *Queue) tryDequeue(fn func(b []byte)) bool {
+	q.lock(&q.consumer, 1)
+	defer q.unlock(&q.consumer, 1)
+
+	tail := atomic.LoadUint64(q.u64(offTail))
+	if tail == atomic.LoadUint64(q.u64(offHead)) {
+		return false
+	}
+	off := tail % q.cap
+	n := binary.LittleEndian.Uint32(q.ring[off:])
+	if n == wrapMark {
+		// the record follows at start of ring, it is published along with the mark.
+		tail += q.cap - off
+		off = 0
+		n = binary.LittleEndian.Uint32(q.ring)
+	}
+	fn(q.ring[off+frameHead : off+frameHead+uint64(n)])
+	atomic.StoreUint64(q.u64(offTail), tail+frameSize(int(n)))
+	atomic.AddInt64(q.i64(offCount), -1)
+	if atomic.LoadInt64(q.i64(offWriteWaiters)) > 0 {
+		notify(q.writable)
+	}
+	return true
+}
+
+// hasRecords return whether ring has records, it is used to wait without lock.
+func (q *Queue) hasRecords() bool {
+	return atomic.LoadUint64(q.u64(offTail)) != atomic.LoadUint64(q.u64(offHead))
+}
+
+// hasSpace return whether a record of size may fit into ring, it is used to wait without lock.
+func (q *Queue) hasSpace(size uint64) bool {
+	_, ok := q.space(atomic.LoadUint64(q.u64(offHead)), atomic.LoadUint64(q.u64(offTail)), size)
+	return ok
+}
+
+// lock exclude other goroutines and processes from one side of MPMC queue, they lock different bytes of the file.
+// locks of open file description are used, so they are released if process died.
+func (q *Queue) lock(mu interface{ Lock() }, b int64) {
+	if !q.mpmc {
+		return
+	}
+	mu.Lock()
+	lk := unix.Flock_t{Type: unix.F_WRLCK, Start: b, Len: 1}
+	for unix.FcntlFlock(q.f.Fd(), unix.F_OFD_SETLKW, &lk) == unix.EINTR {
+	}
+}
+
+func (q *Queue) unlock(mu interface{ Unlock() }, b int64) {
+	if !q.mpmc {
+		return
+	}
+	lk := unix.Flock_t{Type: unix.F_UNLCK, Start: b, Len: 1}
+	unix.FcntlFlock(q.f.Fd(), unix.F_OFD_SETLK, &lk)
+	mu.Unlock()
+}
@@ -0,0 +1,90 @@
+//go:build linux
+
+package qshm
+
+import (
+	"bytes"
+	"path/filepath"
+	"strconv"
+	"testing"
+)
+
+func newQueue(t *testing.T, size int, mpmc bool) (*Queue, string) {
+	path := filepath.Join(t.TempDir(), "q")
+	var (
+		q   *Queue
+		err error
+	)
+	if mpmc {
+		q, err = CreateMPMC(path, size)
+	} else {
+		q, err = Create(path, size)
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		q.Close()
+	})
+	return q, path
+}
+
+func TestFrameSize(t *testing.T) {
+	for n, except := range map[int]uint64{0: 8, 4: 8, 5: 16, 12: 16, 13: 24} {
+		if actual := frameSize(n); actual != except {
+			t.Errorf("%d: except %d, actual %d", n, except, actual)
+		}
+	}
+}
+
+func TestRing_Wrap(t *testing.T) {
+	q, _ := newQueue(t, 256, false)
+	// records of different sizes wrap around the ring many times.
+	next := 0
+	for i := 0; i < 1000; i++ {
+		v := bytes.Repeat([]byte{byte(i)}, i%100)
+		for !q.tryEnqueue(v) {
+			b, ok := q.TryDequeue()
+			if except := bytes.Repeat([]byte{byte(next)}, next%100); !ok || !bytes.Equal(b, except) {
+				t.Fatalf("except %v, actual %v %v", except, b, ok)
+			}
+			next++
+		}
+	}
+	if q.Len() != 1000-next {
+		t.Errorf("except %d, actual %d", 1000-next, q.Len())
+	}
+	for ; next < 1000; next++ {
+		b, ok := q.TryDequeue()
+		if except := bytes.Repeat([]byte{byte(next)}, next%100); !ok || !bytes.Equal(b, except) {
+			t.Fatalf("except %v, actual %v %v", except, b, ok)
+		}
+	}
+	if _, ok := q.TryDequeue(); ok {
+		t.Error("except empty")
+	}
+}
+
+func TestRing_TooLarge(t *testing.T) {
+	q, _ := newQueue(t, 256, false)
+	if _, err := q.TryEnqueue(make([]byte, 125)); err != ErrTooLarge {
+		t.Errorf("except %v, actual %v", ErrTooLarge, err)
+	}
+	if ok, err := q.TryEnqueue(make([]byte, 124)); !ok || err != nil {
+		t.Errorf("except true, actual %v %v", ok, err)
+	}
+}
+
+func TestFits(t *testing.T) {
+	if strconv.IntSize == 32 {
+		t.Skip("record length always fits in 32 bits")
+	}
+	q := &Queue{cap: 1 << 40} // large enough for any record length
+	mark := uint32(wrapMark)
+	max := int(mark)
+	for n, except := range map[int]bool{max - 1: true, max: false, max + 1: false} {
+		if actual := q.fits(n); actual != except {
+			t.Errorf("%d: except %v, actual %v", n, except, actual)
+		}
+	}
+}
@@ -0,0 +1,124 @@
+package qtrace
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"strings"
+
+	"github.com/luweimy/goutil/syncq2"
+)
+
+// Tracer start spans, parent of the span is carried by ctx.
+// it is small enough that an OpenTelemetry adapter can implement it by wrapping trace.Tracer.
+type Tracer interface {
+	Start(ctx context.Context, name string, attrs ...Attr) (context.Context, Span)
+}
+
+// Span is a traced operation, End must be called once the operation done.
+type Span interface {
+	SetAttributes(attrs ...Attr)
+	RecordError(err error)
+	End()
+}
+
+// Attr is key-value attribute of span.
+type Attr struct {
+	Key   string
+	Value interface{}
+}
+
+func String(key, value string) Attr {
+	return Attr{Key: key, Value: value}
+}
+
+func Int64(key string, value int64) Attr {
+	return Attr{Key: key, Value: value}
+}
+
+// Propagator carry span context of ctx through string metadata, e.g. metadata of syncq2 elements.
+type Propagator interface {
+	Inject(ctx context.Context, carrier map[string]string)
+	Extract(ctx context.Context, carrier map[string]string) context.Context
+}
+
+// SpanContext identify span of the built-in Recorder.
+type SpanContext struct {
+	TraceID string // 32 hex digits
+	SpanID  string // 16 hex digits
+}
+
+func (sc SpanContext) IsValid() bool {
+	return len(sc.TraceID) == 32 && len(sc.SpanID) == 16
+}
+
+type spanContextKey struct{}
+
+// ContextWithSpanContext return copy of ctx carries sc, spans started with it are children of sc.
+func ContextWithSpanContext(ctx context.Context, sc SpanContext) context.Context {
+	return context.WithValue(ctx, spanContextKey{}, sc)
+}
+
+// SpanContextFromContext return span context carried by ctx.
+func SpanContextFromContext(ctx context.Context) (SpanContext, bool) {
+	sc, ok := ctx.Value(spanContextKey{}).(SpanContext)
+	return sc, ok
+}
+
+// TraceParentKey is carrier key used by W3C.
+const TraceParentKey = "traceparent"
+
+// W3C propagate SpanContext in W3C traceparent format, e.g. "00-<trace id>-<span id>-01".
+type W3C struct{}
+
+func (W3C) Inject(ctx context.Context, carrier map[string]string) {
+	if sc, ok := SpanContextFromContext(ctx); ok && sc.IsValid() {
+		carrier[TraceParentKey] = "00-" + sc.TraceID + "-" + sc.SpanID + "-01"
+	}
+}
+
+func (W3C) Extract(ctx context.Context, carrier map[string]string) context.Context {
+	parts := strings.Split(carrier[TraceParentKey], "-")
+	if len(parts) != 4 || parts[0] != "00" {
+		return ctx
+	}
+	sc := SpanContext{TraceID: parts[1], SpanID: parts[2]}
+	if !sc.IsValid() || !isHex(sc.TraceID) || !isHex(sc.SpanID) {
+		return ctx
+	}
+	return ContextWithSpanContext(ctx, sc)
+}
+
+// Enqueue put v into q, span context of ctx is injected by p into metadata of the element.
+func Enqueue(ctx context.Context, q *syncq2.SyncQueue, p Propagator, v interface{}) {
+	carrier := make(map[string]string)
+	p.Inject(ctx, carrier)
+	if len(carrier) == 0 {
+		carrier = nil
+	}
+	q.EnqueueWithMeta(v, carrier)
+}
+
+// Dequeue take element from q like DequeueContext,
+// the returned context derives from ctx and carries span context extracted by p from metadata of the element.
+func Dequeue(ctx context.Context, q *syncq2.SyncQueue, p Propagator) (interface{}, context.Context, error) {
+	v, meta, err := q.DequeueWithMeta(ctx)
+	if err != nil {
+		return nil, ctx, err
+	}
+	if meta != nil {
+		ctx = p.Extract(ctx, meta)
+	}
+	return v, ctx, nil
+}
+
+func newID(n int) string {
+	b := make([]byte, n)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+func isHex(s string) bool {
+	_, err := hex.DecodeString(s)
+	return err == nil
+}
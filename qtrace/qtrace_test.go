@@ -0,0 +1,52 @@
+package qtrace
+
+import (
+	"context"
+	"testing"
+
+	"github.com/luweimy/goutil/syncq2"
+)
+
+func TestW3C(t *testing.T) {
+	sc := SpanContext{TraceID: "0af7651916cd43dd8448eb211c80319c", SpanID: "b7ad6b7169203331"}
+	carrier := map[string]string{}
+	W3C{}.Inject(ContextWithSpanContext(context.Background(), sc), carrier)
+	if v := carrier[TraceParentKey]; v != "00-0af7651916cd43dd8448eb211c80319c-b7ad6b7169203331-01" {
+		t.Errorf("except traceparent, actual %q", v)
+	}
+	if got, _ := SpanContextFromContext(W3C{}.Extract(context.Background(), carrier)); got != sc {
+		t.Errorf("except %v, actual %v", sc, got)
+	}
+
+	carrier[TraceParentKey] = "00-xyz-b7ad6b7169203331-01"
+	if _, ok := SpanContextFromContext(W3C{}.Extract(context.Background(), carrier)); ok {
+		t.Error("except invalid traceparent ignored")
+	}
+}
+
+func TestEnqueueDequeue(t *testing.T) {
+	r := NewRecorder()
+	q := syncq2.New()
+	defer q.Destroy()
+
+	ctx, span := r.Start(context.Background(), "produce")
+	Enqueue(ctx, q, r, 1)
+	span.End()
+	Enqueue(context.Background(), q, r, 2)
+
+	v, ctx, err := Dequeue(context.Background(), q, r)
+	if err != nil || v != 1 {
+		t.Fatalf("except 1, actual %v %v", v, err)
+	}
+	_, span = r.Start(ctx, "consume")
+	span.End()
+	spans := r.Spans()
+	if spans[1].TraceID != spans[0].TraceID || spans[1].ParentID != spans[0].SpanID {
+		t.Errorf("except consume is child of produce, actual %+v", spans)
+	}
+
+	v, ctx, err = Dequeue(context.Background(), q, r)
+	if _, ok := SpanContextFromContext(ctx); err != nil || v != 2 || ok {
+		t.Errorf("except 2 without span context, actual %v %v %v", v, ok, err)
+	}
+}
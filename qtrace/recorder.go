@@ -0,0 +1,134 @@
+package qtrace
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"sync"
+	"time"
+)
+
+// SpanData is a finished span recorded by Recorder.
+type SpanData struct {
+	TraceID  string                 `json:"trace_id"`
+	SpanID   string                 `json:"span_id"`
+	ParentID string                 `json:"parent_id,omitempty"`
+	Name     string                 `json:"name"`
+	Start    time.Time              `json:"start"`
+	End      time.Time              `json:"end"`
+	Attrs    map[string]interface{} `json:"attrs,omitempty"`
+	Err      string                 `json:"error,omitempty"`
+}
+
+// Recorder is built-in Tracer records finished spans in memory or writes them to file,
+// use it to test or debug without a collector. it also propagates span context in W3C format.
+type Recorder struct {
+	W3C
+
+	mu    sync.Mutex
+	spans []SpanData
+	enc   *json.Encoder // nil if record in memory
+}
+
+// NewRecorder create Recorder keeps finished spans in memory, get them by Spans.
+func NewRecorder() *Recorder {
+	return &Recorder{}
+}
+
+// NewFileRecorder create Recorder writes finished spans to w as JSON lines, spans are not kept in memory.
+func NewFileRecorder(w io.Writer) *Recorder {
+	return &Recorder{enc: json.NewEncoder(w)}
+}
+
+func (r *Recorder) Start(ctx context.Context, name string, attrs ...Attr) (context.Context, Span) {
+	s := &recordSpan{r: r}
+	s.data.Name = name
+	s.data.Start = time.Now()
+	if parent, ok := SpanContextFromContext(ctx); ok && parent.IsValid() {
+		s.data.TraceID, s.data.ParentID = parent.TraceID, parent.SpanID
+	} else {
+		s.data.TraceID = newID(16)
+	}
+	s.data.SpanID = newID(8)
+	s.SetAttributes(attrs...)
+	return ContextWithSpanContext(ctx, SpanContext{TraceID: s.data.TraceID, SpanID: s.data.SpanID}), s
+}
+
+// Spans return finished spans in order of ending.
+func (r *Recorder) Spans() []SpanData {
+	var spans []SpanData
+	withLock(&r.mu, func() {
+		spans = append(spans, r.spans...)
+	})
+	return spans
+}
+
+// Reset drop finished spans in memory.
+func (r *Recorder) Reset() {
+	withLock(&r.mu, func() {
+		r.spans = nil
+	})
+}
+
+func (r *Recorder) record(data SpanData) {
+	withLock(&r.mu, func() {
+		if r.enc != nil {
+			r.enc.Encode(data)
+			return
+		}
+		r.spans = append(r.spans, data)
+	})
+}
+
+type recordSpan struct {
+	r     *Recorder
+	mu    sync.Mutex
+	data  SpanData
+	ended bool
+}
+
+func (s *recordSpan) SetAttributes(attrs ...Attr) {
+	if len(attrs) == 0 {
+		return
+	}
+	withLock(&s.mu, func() {
+		if s.data.Attrs == nil {
+			s.data.Attrs = make(map[string]interface{}, len(attrs))
+		}
+		for _, attr := range attrs {
+			s.data.Attrs[attr.Key] = attr.Value
+		}
+	})
+}
+
+func (s *recordSpan) RecordError(err error) {
+	if err == nil {
+		return
+	}
+	withLock(&s.mu, func() {
+		s.data.Err = err.Error()
+	})
+}
+
+// End record the span, calls after the first are ignored.
+func (s *recordSpan) End() {
+	var (
+		data  SpanData
+		ended bool
+	)
+	withLock(&s.mu, func() {
+		if ended, s.ended = s.ended, true; !ended {
+			s.data.End = time.Now()
+			data = s.data
+		}
+	})
+	if !ended {
+		s.r.record(data)
+	}
+}
+
+func withLock(lk sync.Locker, fn func()) {
+	lk.Lock()
+	defer lk.Unlock() // in case fn panics
+	fn()
+}
@@ -0,0 +1,52 @@
+package qtrace
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+)
+
+func TestRecorder(t *testing.T) {
+	r := NewRecorder()
+	ctx, parent := r.Start(context.Background(), "parent", String("k", "v"))
+	_, child := r.Start(ctx, "child")
+	child.RecordError(errors.New("failed"))
+	child.End()
+	child.End()
+	parent.SetAttributes(Int64("n", 1))
+	parent.End()
+
+	spans := r.Spans()
+	if len(spans) != 2 {
+		t.Fatalf("except 2 spans, actual %d", len(spans))
+	}
+	c, p := spans[0], spans[1]
+	if c.Name != "child" || c.Err != "failed" || c.ParentID != p.SpanID || c.TraceID != p.TraceID {
+		t.Errorf("child not except, %+v", c)
+	}
+	if p.ParentID != "" || p.Attrs["k"] != "v" || p.Attrs["n"] != int64(1) || p.End.Before(p.Start) {
+		t.Errorf("parent not except, %+v", p)
+	}
+
+	r.Reset()
+	if n := len(r.Spans()); n != 0 {
+		t.Errorf("except 0, actual %d", n)
+	}
+}
+
+func TestNewFileRecorder(t *testing.T) {
+	buf := &bytes.Buffer{}
+	r := NewFileRecorder(buf)
+	_, span := r.Start(context.Background(), "op")
+	span.End()
+
+	var data SpanData
+	if err := json.Unmarshal(buf.Bytes(), &data); err != nil || data.Name != "op" {
+		t.Errorf("except span op, actual %+v %v", data, err)
+	}
+	if n := len(r.Spans()); n != 0 {
+		t.Errorf("except spans not kept in memory, actual %d", n)
+	}
+}
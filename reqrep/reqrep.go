@@ -0,0 +1,155 @@
+package reqrep
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/luweimy/goutil/syncq2"
+)
+
+// ErrClosed is returned when request by closed Requester.
+var ErrClosed = errors.New("reqrep: requester closed")
+
+// lastRequesterID is used to generate unique correlation id prefix of requesters.
+var lastRequesterID uint64
+
+// Message is enqueued to request queue and reply queue.
+type Message struct {
+	CorrelationID string
+	ReplyTo       *syncq2.SyncQueue // queue to enqueue reply, nil in reply message
+	Body          interface{}
+	Err           error // error of handler, only in reply message
+}
+
+// Requester enqueue request messages and wait matching replies on its reply queue.
+type Requester struct {
+	ctx     context.Context
+	cancel  context.CancelFunc
+	id      uint64
+	seq     uint64
+	replies *syncq2.SyncQueue
+
+	mu      sync.Mutex
+	pending map[string]chan *Message
+}
+
+// NewRequester create Requester, a goroutine is started to dispatch replies, use Close to stop it.
+func NewRequester() *Requester {
+	ctx, cancel := context.WithCancel(context.Background())
+	r := &Requester{
+		ctx:     ctx,
+		cancel:  cancel,
+		id:      atomic.AddUint64(&lastRequesterID, 1),
+		replies: syncq2.New(),
+		pending: make(map[string]chan *Message),
+	}
+	go r.dispatchReplies()
+	return r
+}
+
+// Request enqueue body to q and block until reply received or ctx done.
+func (r *Requester) Request(ctx context.Context, q *syncq2.SyncQueue, body interface{}) (interface{}, error) {
+	if r.ctx.Err() != nil {
+		return nil, ErrClosed
+	}
+	id := fmt.Sprintf("%d-%d", r.id, atomic.AddUint64(&r.seq, 1))
+	reply := make(chan *Message, 1)
+	withLock(&r.mu, func() {
+		r.pending[id] = reply
+	})
+	defer withLock(&r.mu, func() {
+		delete(r.pending, id)
+	})
+
+	q.Enqueue(&Message{
+		CorrelationID: id,
+		ReplyTo:       r.replies,
+		Body:          body,
+	})
+	select {
+	case msg := <-reply:
+		return msg.Body, msg.Err
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	case <-r.ctx.Done():
+		return nil, ErrClosed
+	}
+}
+
+// RequestTimeout is like Request, but wait reply at most timeout.
+func (r *Requester) RequestTimeout(q *syncq2.SyncQueue, body interface{}, timeout time.Duration) (interface{}, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	return r.Request(ctx, q, body)
+}
+
+// Close stop dispatching replies, pending requests return ErrClosed.
+func (r *Requester) Close() {
+	r.cancel()
+	r.replies.Destroy()
+}
+
+func (r *Requester) dispatchReplies() {
+	for {
+		v, err := r.replies.DequeueContext(r.ctx)
+		if err != nil {
+			return
+		}
+		msg := v.(*Message)
+		var reply chan *Message
+		withLock(&r.mu, func() {
+			reply = r.pending[msg.CorrelationID]
+		})
+		if reply == nil {
+			// late reply of timeout request is dropped.
+			continue
+		}
+		select {
+		case reply <- msg:
+		default: // duplicated reply
+		}
+	}
+}
+
+// HandlerFunc handle request body and return reply body.
+type HandlerFunc func(ctx context.Context, body interface{}) (interface{}, error)
+
+// Serve dequeue request messages from q and reply with handler results,
+// it blocks until ctx done or q destroyed. run Serve in multiple goroutines to handle concurrently.
+func Serve(ctx context.Context, q *syncq2.SyncQueue, handler HandlerFunc) error {
+	for {
+		v, err := q.DequeueContext(ctx)
+		if err != nil {
+			return err
+		}
+		msg, ok := v.(*Message)
+		if !ok || msg.ReplyTo == nil {
+			continue
+		}
+		body, err := handle(ctx, handler, msg.Body)
+		msg.ReplyTo.Enqueue(&Message{
+			CorrelationID: msg.CorrelationID,
+			Body:          body,
+			Err:           err,
+		})
+	}
+}
+
+func handle(ctx context.Context, handler HandlerFunc, body interface{}) (reply interface{}, err error) {
+	defer func() { // in case handler panics
+		if r := recover(); r != nil {
+			err = fmt.Errorf("panic: %v", r)
+		}
+	}()
+	return handler(ctx, body)
+}
+
+func withLock(lk sync.Locker, fn func()) {
+	lk.Lock()
+	defer lk.Unlock() // in case fn panics
+	fn()
+}
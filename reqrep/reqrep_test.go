@@ -0,0 +1,61 @@
+package reqrep
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/luweimy/goutil/syncq2"
+)
+
+func TestRequester_Request(t *testing.T) {
+	q := syncq2.New()
+	defer q.Destroy()
+
+	errOdd := errors.New("odd")
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	for i := 0; i < 2; i++ {
+		go Serve(ctx, q, func(ctx context.Context, body interface{}) (interface{}, error) {
+			if body.(int)%2 == 1 {
+				return nil, errOdd
+			}
+			return body.(int) * 10, nil
+		})
+	}
+
+	r := NewRequester()
+	defer r.Close()
+
+	wg := sync.WaitGroup{}
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			reply, err := r.RequestTimeout(q, i, time.Second)
+			if i%2 == 1 && err != errOdd {
+				t.Errorf("except %v, actual %v", errOdd, err)
+			}
+			if i%2 == 0 && reply != i*10 {
+				t.Errorf("except %v, actual %v %v", i*10, reply, err)
+			}
+		}(i)
+	}
+	wg.Wait()
+}
+
+func TestRequester_RequestTimeout(t *testing.T) {
+	q := syncq2.New() // no responder
+	r := NewRequester()
+
+	if _, err := r.RequestTimeout(q, 1, time.Millisecond*50); err != context.DeadlineExceeded {
+		t.Errorf("except %v, actual %v", context.DeadlineExceeded, err)
+	}
+
+	r.Close()
+	if _, err := r.RequestTimeout(q, 1, time.Millisecond*50); err != ErrClosed {
+		t.Errorf("except %v, actual %v", ErrClosed, err)
+	}
+}
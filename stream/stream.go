@@ -0,0 +1,264 @@
+package stream
+
+import (
+	"context"
+	"time"
+
+	"github.com/luweimy/goutil/syncq2"
+)
+
+// Operators take an input channel and produce a new output channel.
+// the output channel is closed and the goroutine exits when input closed or ctx done.
+
+// FromQueue dequeue items from q, until q destroyed or ctx done.
+func FromQueue(ctx context.Context, q *syncq2.SyncQueue) <-chan interface{} {
+	out := make(chan interface{})
+	go func() {
+		defer close(out)
+		for {
+			v, err := q.DequeueContext(ctx)
+			if err != nil || !send(ctx, out, v) {
+				return
+			}
+		}
+	}()
+	return out
+}
+
+// ToQueue enqueue items of in to a new queue, until in closed or ctx done.
+// the queue is destroyed then, items left in it can still be dequeued by DequeueContext or FromQueue,
+// which return once it drained.
+func ToQueue(ctx context.Context, in <-chan interface{}) *syncq2.SyncQueue {
+	q := syncq2.New()
+	go func() {
+		defer q.Destroy()
+		for {
+			v, ok := recv(ctx, in)
+			if !ok {
+				return
+			}
+			q.Enqueue(v)
+		}
+	}()
+	return q
+}
+
+// Map emit fn(item) for each item.
+func Map(ctx context.Context, in <-chan interface{}, fn func(interface{}) interface{}) <-chan interface{} {
+	return FlatMap(ctx, in, func(v interface{}) []interface{} {
+		return []interface{}{fn(v)}
+	})
+}
+
+// Filter emit items fn returns true.
+func Filter(ctx context.Context, in <-chan interface{}, fn func(interface{}) bool) <-chan interface{} {
+	return FlatMap(ctx, in, func(v interface{}) []interface{} {
+		if fn(v) {
+			return []interface{}{v}
+		}
+		return nil
+	})
+}
+
+// FlatMap emit all items fn returns for each item.
+func FlatMap(ctx context.Context, in <-chan interface{}, fn func(interface{}) []interface{}) <-chan interface{} {
+	out := make(chan interface{})
+	go func() {
+		defer close(out)
+		for {
+			v, ok := recv(ctx, in)
+			if !ok {
+				return
+			}
+			for _, item := range fn(v) {
+				if !send(ctx, out, item) {
+					return
+				}
+			}
+		}
+	}()
+	return out
+}
+
+// Buffer emit []interface{} once n items collected or d elapsed since the first item of buffer,
+// n <= 0 means unlimited, d <= 0 means no time limit.
+func Buffer(ctx context.Context, in <-chan interface{}, n int, d time.Duration) <-chan interface{} {
+	out := make(chan interface{})
+	go func() {
+		defer close(out)
+		var (
+			buf   []interface{}
+			timer = stoppedTimer()
+		)
+		defer timer.Stop()
+		flush := func() bool {
+			timer.Stop()
+			if len(buf) == 0 {
+				return true
+			}
+			items := buf
+			buf = nil
+			return send(ctx, out, items)
+		}
+		for {
+			select {
+			case v, ok := <-in:
+				if !ok {
+					flush()
+					return
+				}
+				buf = append(buf, v)
+				if len(buf) == 1 && d > 0 {
+					timer.Reset(d)
+				}
+				if n > 0 && len(buf) >= n && !flush() {
+					return
+				}
+			case <-timer.C:
+				if !flush() {
+					return
+				}
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return out
+}
+
+// Throttle emit the first item of every d, the others in the duration are dropped.
+func Throttle(ctx context.Context, in <-chan interface{}, d time.Duration) <-chan interface{} {
+	var last time.Time
+	return Filter(ctx, in, func(v interface{}) bool {
+		if now := time.Now(); now.Sub(last) >= d {
+			last = now
+			return true
+		}
+		return false
+	})
+}
+
+// Debounce emit the latest item only after no item received in d.
+func Debounce(ctx context.Context, in <-chan interface{}, d time.Duration) <-chan interface{} {
+	out := make(chan interface{})
+	go func() {
+		defer close(out)
+		var (
+			latest  interface{}
+			pending bool
+			timer   = stoppedTimer()
+		)
+		defer timer.Stop()
+		for {
+			select {
+			case v, ok := <-in:
+				if !ok {
+					if pending {
+						send(ctx, out, latest)
+					}
+					return
+				}
+				latest, pending = v, true
+				timer.Reset(d)
+			case <-timer.C:
+				if !pending {
+					continue
+				}
+				pending = false
+				if !send(ctx, out, latest) {
+					return
+				}
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return out
+}
+
+// Window emit []interface{} of items received in every tumbling window of size,
+// empty windows are not emitted. it panics if size is not positive.
+func Window(ctx context.Context, in <-chan interface{}, size time.Duration) <-chan interface{} {
+	return SlidingWindow(ctx, in, size, size)
+}
+
+// SlidingWindow emit []interface{} of items received in the last size every slide,
+// empty windows are not emitted. it panics if size or slide is not positive.
+func SlidingWindow(ctx context.Context, in <-chan interface{}, size, slide time.Duration) <-chan interface{} {
+	if size <= 0 || slide <= 0 {
+		// panic in caller's goroutine rather than the operator goroutine crashes the process.
+		panic("stream: non-positive window size or slide")
+	}
+	type entry struct {
+		v interface{}
+		t time.Time
+	}
+	out := make(chan interface{})
+	go func() {
+		defer close(out)
+		var entries []entry
+		ticker := time.NewTicker(slide)
+		defer ticker.Stop()
+		emit := func(now time.Time) bool {
+			// evict items out of window, items of tumbling window are all in window.
+			i := 0
+			for size != slide && i < len(entries) && now.Sub(entries[i].t) > size {
+				i++
+			}
+			entries = entries[i:]
+			if len(entries) == 0 {
+				return true
+			}
+			items := make([]interface{}, 0, len(entries))
+			for _, e := range entries {
+				items = append(items, e.v)
+			}
+			return send(ctx, out, items)
+		}
+		for {
+			select {
+			case v, ok := <-in:
+				if !ok {
+					emit(time.Now())
+					return
+				}
+				entries = append(entries, entry{v: v, t: time.Now()})
+			case now := <-ticker.C:
+				if !emit(now) {
+					return
+				}
+				if size == slide {
+					// tumbling window, items are emitted only once.
+					entries = nil
+				}
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return out
+}
+
+func send(ctx context.Context, out chan<- interface{}, v interface{}) bool {
+	select {
+	case out <- v:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
+func recv(ctx context.Context, in <-chan interface{}) (interface{}, bool) {
+	select {
+	case v, ok := <-in:
+		return v, ok
+	case <-ctx.Done():
+		return nil, false
+	}
+}
+
+func stoppedTimer() *time.Timer {
+	timer := time.NewTimer(time.Hour)
+	timer.Stop()
+	return timer
+}
@@ -0,0 +1,174 @@
+package stream
+
+import (
+	"context"
+	"reflect"
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/luweimy/goutil/syncq2"
+)
+
+func from(values ...interface{}) <-chan interface{} {
+	ch := make(chan interface{}, len(values))
+	for _, v := range values {
+		ch <- v
+	}
+	close(ch)
+	return ch
+}
+
+func collect(ch <-chan interface{}) []interface{} {
+	var items []interface{}
+	for v := range ch {
+		items = append(items, v)
+	}
+	return items
+}
+
+func TestMapFilterFlatMap(t *testing.T) {
+	ctx := context.Background()
+	out := Map(ctx, from(1, 2, 3, 4), func(v interface{}) interface{} {
+		return v.(int) * 10
+	})
+	out = Filter(ctx, out, func(v interface{}) bool {
+		return v.(int) > 10
+	})
+	out = FlatMap(ctx, out, func(v interface{}) []interface{} {
+		return []interface{}{v, v}
+	})
+	except := []interface{}{20, 20, 30, 30, 40, 40}
+	if items := collect(out); !reflect.DeepEqual(items, except) {
+		t.Errorf("except %v, actual %v", except, items)
+	}
+}
+
+func TestBuffer(t *testing.T) {
+	ctx := context.Background()
+	in := make(chan interface{})
+	out := Buffer(ctx, in, 2, time.Millisecond*50)
+	go func() {
+		in <- 1
+		in <- 2
+		in <- 3
+		time.Sleep(time.Millisecond * 100)
+		close(in)
+	}()
+	except := []interface{}{[]interface{}{1, 2}, []interface{}{3}}
+	if items := collect(out); !reflect.DeepEqual(items, except) {
+		t.Errorf("except %v, actual %v", except, items)
+	}
+}
+
+func TestThrottleDebounce(t *testing.T) {
+	ctx := context.Background()
+	if items := collect(Throttle(ctx, from(1, 2, 3), time.Second)); !reflect.DeepEqual(items, []interface{}{1}) {
+		t.Errorf("except [1], actual %v", items)
+	}
+
+	in := make(chan interface{})
+	out := Debounce(ctx, in, time.Millisecond*50)
+	go func() {
+		in <- 1
+		in <- 2
+		time.Sleep(time.Millisecond * 100)
+		in <- 3
+		close(in)
+	}()
+	if items := collect(out); !reflect.DeepEqual(items, []interface{}{2, 3}) {
+		t.Errorf("except [2 3], actual %v", items)
+	}
+}
+
+func TestWindow(t *testing.T) {
+	ctx := context.Background()
+	in := make(chan interface{})
+	out := Window(ctx, in, time.Millisecond*100)
+	go func() {
+		in <- 1
+		in <- 2
+		time.Sleep(time.Millisecond * 150)
+		in <- 3
+		close(in)
+	}()
+	except := []interface{}{[]interface{}{1, 2}, []interface{}{3}}
+	if items := collect(out); !reflect.DeepEqual(items, except) {
+		t.Errorf("except %v, actual %v", except, items)
+	}
+}
+
+func TestWindow_Invalid(t *testing.T) {
+	for _, d := range [][2]time.Duration{{0, time.Second}, {time.Second, 0}, {-1, -1}} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("%v: except panic in caller", d)
+				}
+			}()
+			SlidingWindow(context.Background(), from(), d[0], d[1])
+		}()
+	}
+}
+
+func TestSlidingWindow(t *testing.T) {
+	ctx := context.Background()
+	in := make(chan interface{})
+	out := SlidingWindow(ctx, in, time.Millisecond*100, time.Millisecond*60)
+	go func() {
+		in <- 1
+		time.Sleep(time.Millisecond * 90)
+		in <- 2
+		time.Sleep(time.Millisecond * 60)
+		close(in)
+	}()
+	// window at 60ms [1], window at 120ms [2] (1 is evicted), [2] again when in closed.
+	items := collect(out)
+	if len(items) < 2 || !reflect.DeepEqual(items[0], []interface{}{1}) || !reflect.DeepEqual(items[1], []interface{}{2}) {
+		t.Errorf("except [[1] [2] ...], actual %v", items)
+	}
+}
+
+func TestFromQueue(t *testing.T) {
+	numgo := runtime.NumGoroutine()
+	q := syncq2.New()
+	q.Enqueue(1)
+	out := Map(context.Background(), FromQueue(context.Background(), q), func(v interface{}) interface{} {
+		return v.(int) + 1
+	})
+	if v := <-out; v != 2 {
+		t.Errorf("except 2, actual %v", v)
+	}
+
+	// destroy input queue, all operator goroutines exit.
+	q.Destroy()
+	if _, ok := <-out; ok {
+		t.Error("except output closed")
+	}
+	time.Sleep(time.Millisecond * 10)
+	if n := runtime.NumGoroutine(); n > numgo {
+		t.Errorf("except %v goroutines, actual %v", numgo, n)
+	}
+
+	out2 := ToQueue(context.Background(), from(1))
+	if v := out2.Dequeue(); v != 1 {
+		t.Errorf("except 1, actual %v", v)
+	}
+}
+
+func TestToQueue(t *testing.T) {
+	// input closed, FromQueue drains the queue and terminates.
+	out := FromQueue(context.Background(), ToQueue(context.Background(), from(1, 2, 3)))
+	done := make(chan []interface{})
+	go func() {
+		done <- collect(out)
+	}()
+	select {
+	case items := <-done:
+		if except := []interface{}{1, 2, 3}; !reflect.DeepEqual(items, except) {
+			t.Errorf("except %v, actual %v", except, items)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("except pipeline terminated")
+	}
+}
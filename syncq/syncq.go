@@ -3,6 +3,12 @@ package syncq
 import (
 	"container/list"
 	"context"
+	"log/slog"
+	"sync/atomic"
+	"time"
+
+	"github.com/luweimy/goutil/internal/watermark"
+	"github.com/luweimy/goutil/observer"
 )
 
 // SyncQueue相当于容量可无限制的channel
@@ -17,12 +23,29 @@ type SyncQueue struct {
 	max int
 	in  chan interface{} // use to enqueue
 	out chan interface{} // use to dequeue
+
+	obs    observer.Observer // nil if not observed
+	n      int64             // length of list, use to read length out of dispatch goroutine
+	logger atomic.Pointer[slog.Logger]
+	wm     watermark.Watermark
+	empty  watermark.Watermark // above if not empty
+}
+
+// element is stored in list when queue is observed, use to compute wait time.
+type element struct {
+	value interface{}
+	t     time.Time
 }
 
 // max代表队列元素个数上限，若小于等于0，则队列无元素上限
 // 内部会启动一个goroutine用于channel同步，可用Destroy()方法销毁。
 // 注意调用Destroy()后就不可执行入队出队操作，否则会一直阻塞下去。
 func NewWithSize(max int) *SyncQueue {
+	return NewWithObserver(max, nil)
+}
+
+// NewWithObserver 与NewWithSize相同，并将入队、出队及销毁时丢弃元素的事件通知给obs
+func NewWithObserver(max int, obs observer.Observer) *SyncQueue {
 	ctx, cancel := context.WithCancel(context.Background())
 	q := &SyncQueue{
 		ctx:    ctx,
@@ -32,6 +55,10 @@ func NewWithSize(max int) *SyncQueue {
 		in:     make(chan interface{}),
 		out:    make(chan interface{}),
 	}
+	q.empty.Set(1, 0, nil)
+	if observer.Enabled(obs) {
+		q.obs = obs
+	}
 	go q.dispatch()
 	return q
 }
@@ -46,7 +73,7 @@ func (q *SyncQueue) dispatch() {
 			// the queue is empty, only enqueue is allowed.
 			select {
 			case v := <-q.in:
-				q.l.PushBack(v)
+				q.push(v)
 			case <-q.ctx.Done():
 				return
 			}
@@ -55,25 +82,76 @@ func (q *SyncQueue) dispatch() {
 		if q.max > 0 && q.l.Len() >= q.max {
 			// the queue is full, only dequeue is allowed.
 			select {
-			case q.out <- e.Value:
-				q.l.Remove(e)
+			case q.out <- q.value(e):
+				q.remove(e)
 			case <-q.ctx.Done():
+				q.drop()
 				return
 			}
 		} else {
 			// enqueue and dequeue are allowed.
 			select {
 			case value := <-q.in:
-				q.l.PushBack(value)
-			case q.out <- e.Value:
-				q.l.Remove(e)
+				q.push(value)
+			case q.out <- q.value(e):
+				q.remove(e)
 			case <-q.ctx.Done():
+				q.drop()
 				return
 			}
 		}
 	}
 }
 
+func (q *SyncQueue) push(v interface{}) {
+	atomic.AddInt64(&q.n, 1)
+	if q.obs == nil {
+		q.l.PushBack(v)
+	} else {
+		now := time.Now()
+		q.l.PushBack(element{value: v, t: now})
+		q.obs.OnEnqueue(observer.QueueEvent{Time: now, Len: q.l.Len()})
+	}
+	q.wm.Update(q.l.Len())
+	q.empty.Update(q.l.Len())
+}
+
+func (q *SyncQueue) value(e *list.Element) interface{} {
+	if q.obs == nil {
+		return e.Value
+	}
+	return e.Value.(element).value
+}
+
+func (q *SyncQueue) remove(e *list.Element) {
+	q.l.Remove(e)
+	atomic.AddInt64(&q.n, -1)
+	if q.obs != nil {
+		now := time.Now()
+		q.obs.OnDequeue(observer.QueueEvent{Time: now, Len: q.l.Len(), Wait: now.Sub(e.Value.(element).t)})
+	}
+	q.wm.Update(q.l.Len())
+	q.empty.Update(q.l.Len())
+}
+
+// drop report elements left in queue when destroyed.
+func (q *SyncQueue) drop() {
+	atomic.StoreInt64(&q.n, 0)
+	q.wm.Update(0)
+	q.empty.Update(0)
+	if l := q.logger.Load(); l != nil {
+		l.Warn("queue destroyed, elements dropped", slog.Int("dropped", q.l.Len()), slog.Int("max", q.max))
+	}
+	if q.obs == nil {
+		return
+	}
+	now := time.Now()
+	for n, e := q.l.Len(), q.l.Front(); e != nil; e = e.Next() {
+		n--
+		q.obs.OnDrop(observer.QueueEvent{Time: now, Len: n, Wait: now.Sub(e.Value.(element).t)})
+	}
+}
+
 func (q *SyncQueue) Enqueue(value interface{}) {
 	q.in <- value
 }
@@ -90,6 +168,41 @@ func (q *SyncQueue) DequeueC() <-chan interface{} {
 	return q.out
 }
 
+// SetLogger 设置日志，销毁队列时丢弃的元素会被记录，nil表示不记录日志
+func (q *SyncQueue) SetLogger(l *slog.Logger) *SyncQueue {
+	q.logger.Store(l)
+	return q
+}
+
+// SetWatermarks 设置高低水位，队列长度涨到high时调用fn(true, n)，之后回落到low时调用fn(false, n)，
+// 生产者可据此提前暂停和恢复接收。fn可为nil，在队列内部同步调用，不能阻塞也不能调用队列接口；high<=0表示关闭
+func (q *SyncQueue) SetWatermarks(high, low int, fn func(high bool, n int)) *SyncQueue {
+	q.wm.Set(high, low, fn)
+	return q
+}
+
+// SubscribeWatermarks 返回的channel在队列长度涨到高水位时收到true，回落到低水位时收到false，
+// 读取不及时只保留最新状态，调用返回的函数取消订阅并关闭channel
+func (q *SyncQueue) SubscribeWatermarks() (<-chan bool, func()) {
+	return q.wm.Subscribe()
+}
+
+// WaitEmpty 阻塞直到队列为空或ctx取消，返回ctx的错误
+func (q *SyncQueue) WaitEmpty(ctx context.Context) error {
+	return q.empty.WaitBelow(ctx)
+}
+
+// SubscribeEmpty 返回的channel在队列变为空时收到true，变为非空时收到false，
+// 读取不及时只保留最新状态，调用返回的函数取消订阅并关闭channel
+func (q *SyncQueue) SubscribeEmpty() (<-chan bool, func()) {
+	return q.empty.SubscribeBelow()
+}
+
+// Len 返回队列中元素个数
+func (q *SyncQueue) Len() int {
+	return int(atomic.LoadInt64(&q.n))
+}
+
 func (q *SyncQueue) Destroy() {
 	// cancel dispatch goroutine
 	q.cancel()
@@ -1,11 +1,17 @@
 package syncq
 
 import (
+	"context"
 	"errors"
+	"fmt"
+	"log/slog"
 	"runtime"
+	"strings"
 	"sync"
 	"testing"
 	"time"
+
+	"github.com/luweimy/goutil/observer"
 )
 
 func TestSyncQueue(t *testing.T) {
@@ -141,3 +147,116 @@ func BenchmarkChannelNoBufferWithSelect(b *testing.B) {
 		ch <- struct{}{}
 	}
 }
+
+type recordObserver struct {
+	observer.Nop
+	events chan string
+}
+
+func (o *recordObserver) OnEnqueue(e observer.QueueEvent) { o.events <- fmt.Sprint("enqueue ", e.Len) }
+func (o *recordObserver) OnDequeue(e observer.QueueEvent) { o.events <- fmt.Sprint("dequeue ", e.Len) }
+func (o *recordObserver) OnDrop(e observer.QueueEvent)    { o.events <- fmt.Sprint("drop ", e.Len) }
+
+func TestNewWithObserver(t *testing.T) {
+	obs := &recordObserver{events: make(chan string, 10)}
+	q := NewWithObserver(0, obs)
+	q.Enqueue(1)
+	q.Enqueue(2)
+	q.Enqueue(3)
+	if v := q.Dequeue(); v != 1 {
+		t.Errorf("except 1, actual %v", v)
+	}
+	q.Destroy()
+
+	except := []string{"enqueue 1", "enqueue 2", "enqueue 3", "dequeue 2", "drop 1", "drop 0"}
+	for _, e := range except {
+		select {
+		case actual := <-obs.events:
+			if actual != e {
+				t.Errorf("except %v, actual %v", e, actual)
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("except %v, actual nothing", e)
+		}
+	}
+}
+
+// chanWriter send written logs to channel, in case of logging in dispatch goroutine.
+type chanWriter chan string
+
+func (w chanWriter) Write(p []byte) (int, error) {
+	w <- string(p)
+	return len(p), nil
+}
+
+func TestSyncQueue_SetLogger(t *testing.T) {
+	logs := make(chanWriter, 1)
+	q := NewWithSize(2).SetLogger(slog.New(slog.NewTextHandler(logs, nil)))
+	q.Enqueue(1)
+	q.Enqueue(2)
+	q.Destroy()
+
+	if s, log := `level=WARN msg="queue destroyed, elements dropped" dropped=2 max=2`, <-logs; !strings.Contains(log, s) {
+		t.Errorf("except %q in log: %s", s, log)
+	}
+}
+
+func TestSyncQueue_SetWatermarks(t *testing.T) {
+	events := make(chan bool, 4)
+	q := NewWithSize(4).SetWatermarks(3, 1, func(high bool, n int) {
+		events <- high
+	})
+	defer q.Destroy()
+	ch, unsubscribe := q.SubscribeWatermarks()
+	defer unsubscribe()
+
+	for i := 0; i < 3; i++ {
+		q.Enqueue(i)
+	}
+	if v := <-events; !v {
+		t.Error("except high watermark crossed")
+	}
+	if v := <-ch; !v {
+		t.Error("except subscriber receives high")
+	}
+	q.Dequeue()
+	q.Dequeue()
+	if v := <-events; v {
+		t.Error("except low watermark crossed")
+	}
+	if v := <-ch; v {
+		t.Error("except subscriber receives low")
+	}
+}
+
+func TestSyncQueue_WaitEmpty(t *testing.T) {
+	q := New()
+	defer q.Destroy()
+	if err := q.WaitEmpty(context.Background()); err != nil {
+		t.Errorf("except nil, actual %v", err)
+	}
+	ch, unsubscribe := q.SubscribeEmpty()
+	defer unsubscribe()
+
+	q.Enqueue(1)
+	q.Enqueue(2)
+	if v := <-ch; v {
+		t.Error("except non-empty transition")
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond*10)
+	defer cancel()
+	if err := q.WaitEmpty(ctx); err != context.DeadlineExceeded {
+		t.Errorf("except %v, actual %v", context.DeadlineExceeded, err)
+	}
+
+	go func() {
+		q.Dequeue()
+		q.Dequeue()
+	}()
+	if err := q.WaitEmpty(context.Background()); err != nil || q.Len() != 0 {
+		t.Errorf("except empty, actual %v %d", err, q.Len())
+	}
+	if v := <-ch; !v {
+		t.Error("except empty transition")
+	}
+}
@@ -3,9 +3,19 @@ package syncq2
 import (
 	"container/list"
 	"context"
+	"errors"
+	"log/slog"
 	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/luweimy/goutil/internal/watermark"
+	"github.com/luweimy/goutil/observer"
 )
 
+// ErrDestroyed 队列已销毁
+var ErrDestroyed = errors.New("syncq2: queue destroyed")
+
 // SyncQueue相当于无容量限制的channel
 // Enqueue 接口将元素放入队列中，不会发生阻塞
 // Dequeue 接口会阻塞直到队列中有元素返回，阻塞的情况只在队列空的时候才会出现
@@ -19,9 +29,26 @@ type SyncQueue struct {
 	out     chan interface{}
 	inOnce  sync.Once
 	outOnce sync.Once
+
+	obs    observer.Observer // nil if not observed
+	logger atomic.Pointer[slog.Logger]
+	wm     watermark.Watermark
+	empty  watermark.Watermark // above if not empty
+}
+
+// element is stored in list when queue is observed or value carries metadata.
+type element struct {
+	value interface{}
+	t     time.Time
+	meta  map[string]string
 }
 
 func New() *SyncQueue {
+	return NewWithObserver(nil)
+}
+
+// NewWithObserver 与New相同，并将入队、出队事件通知给obs
+func NewWithObserver(obs observer.Observer) *SyncQueue {
 	ctx, cancel := context.WithCancel(context.Background())
 	q := &SyncQueue{
 		ctx:    ctx,
@@ -29,12 +56,29 @@ func New() *SyncQueue {
 		l:      list.New(),
 		cond:   sync.NewCond(&sync.Mutex{}),
 	}
+	q.empty.Set(1, 0, nil)
+	if observer.Enabled(obs) {
+		q.obs = obs
+	}
 	return q
 }
 
 func (q *SyncQueue) Enqueue(value interface{}) {
+	q.EnqueueWithMeta(value, nil)
+}
+
+// EnqueueWithMeta 与Enqueue相同，元素同时携带元数据meta（如trace上下文），通过DequeueWithMeta取出
+func (q *SyncQueue) EnqueueWithMeta(value interface{}, meta map[string]string) {
 	withLock(q.cond.L, func() {
-		q.l.PushBack(value)
+		q.push(value, meta)
+		q.cond.Signal()
+	})
+}
+
+// EnqueueFront 将元素放到队列头部，下一次出队时最先返回
+func (q *SyncQueue) EnqueueFront(value interface{}) {
+	withLock(q.cond.L, func() {
+		q.pushFront(value)
 		q.cond.Signal()
 	})
 }
@@ -46,11 +90,124 @@ func (q *SyncQueue) Dequeue() interface{} {
 		for q.l.Len() <= 0 {
 			q.cond.Wait()
 		}
-		v = q.l.Remove(q.l.Front())
+		v, _ = q.remove(q.l.Front())
 	})
 	return v
 }
 
+// DequeueContext 与Dequeue相同，但在ctx取消或队列销毁时不再阻塞，返回对应的错误
+func (q *SyncQueue) DequeueContext(ctx context.Context) (interface{}, error) {
+	v, _, err := q.DequeueWithMeta(ctx)
+	return v, err
+}
+
+// DequeueWithMeta 与DequeueContext相同，同时返回元素入队时携带的元数据，没有元数据时为nil
+func (q *SyncQueue) DequeueWithMeta(ctx context.Context) (interface{}, map[string]string, error) {
+	// wake up waiting when ctx canceled, broadcast with lock in case of missing wakeup.
+	stop := context.AfterFunc(ctx, func() {
+		withLock(q.cond.L, q.cond.Broadcast)
+	})
+	defer stop()
+
+	var (
+		v    interface{}
+		meta map[string]string
+		err  error
+	)
+	withLock(q.cond.L, func() {
+		for q.l.Len() <= 0 {
+			if err = ctx.Err(); err != nil {
+				return
+			}
+			if q.ctx.Err() != nil {
+				err = ErrDestroyed
+				return
+			}
+			q.cond.Wait()
+		}
+		v, meta = q.remove(q.l.Front())
+	})
+	return v, meta, err
+}
+
+// TryDequeue 从队列头部取出元素，不会阻塞，队列为空时返回false
+func (q *SyncQueue) TryDequeue() (interface{}, bool) {
+	return q.tryRemove(q.l.Front)
+}
+
+// TryDequeueBack 从队列尾部取出元素，不会阻塞，队列为空时返回false
+func (q *SyncQueue) TryDequeueBack() (interface{}, bool) {
+	return q.tryRemove(q.l.Back)
+}
+
+func (q *SyncQueue) tryRemove(at func() *list.Element) (interface{}, bool) {
+	var (
+		v  interface{}
+		ok bool
+	)
+	withLock(q.cond.L, func() {
+		if e := at(); e != nil {
+			v, _ = q.remove(e)
+			ok = true
+		}
+	})
+	return v, ok
+}
+
+// Clear 清空队列，返回清除的元素个数
+func (q *SyncQueue) Clear() int {
+	var n int
+	withLock(q.cond.L, func() {
+		for e := q.l.Front(); e != nil; e = q.l.Front() {
+			q.remove(e)
+			n++
+		}
+	})
+	return n
+}
+
+// push must be called with lock held.
+func (q *SyncQueue) push(v interface{}, meta map[string]string) {
+	q.insert(v, meta, q.l.PushBack)
+}
+
+// pushFront must be called with lock held.
+func (q *SyncQueue) pushFront(v interface{}) {
+	q.insert(v, nil, q.l.PushFront)
+}
+
+func (q *SyncQueue) insert(v interface{}, meta map[string]string, at func(v interface{}) *list.Element) {
+	switch {
+	case q.obs != nil:
+		now := time.Now()
+		at(element{value: v, t: now, meta: meta})
+		q.obs.OnEnqueue(observer.QueueEvent{Time: now, Len: q.l.Len()})
+	case meta != nil:
+		at(element{value: v, meta: meta})
+	default:
+		at(v)
+	}
+	q.wm.Update(q.l.Len())
+	q.empty.Update(q.l.Len())
+}
+
+// remove must be called with lock held.
+func (q *SyncQueue) remove(e *list.Element) (interface{}, map[string]string) {
+	v := q.l.Remove(e)
+	q.wm.Update(q.l.Len())
+	q.empty.Update(q.l.Len())
+	// element is unexported, values of caller can not be mistaken for it.
+	elem, ok := v.(element)
+	if !ok {
+		return v, nil
+	}
+	if q.obs != nil {
+		now := time.Now()
+		q.obs.OnDequeue(observer.QueueEvent{Time: now, Len: q.l.Len(), Wait: now.Sub(elem.t)})
+	}
+	return elem.value, elem.meta
+}
+
 func (q *SyncQueue) EnqueueC() chan<- interface{} {
 	if q.in == nil {
 		q.inOnce.Do(func() {
@@ -89,9 +246,57 @@ func (q *SyncQueue) DequeueC() <-chan interface{} {
 	return q.out
 }
 
+// SetLogger 设置日志，销毁时队列中仍有元素会被记录，nil表示不记录日志
+func (q *SyncQueue) SetLogger(l *slog.Logger) *SyncQueue {
+	q.logger.Store(l)
+	return q
+}
+
+// SetWatermarks 设置高低水位，队列长度涨到high时调用fn(true, n)，之后回落到low时调用fn(false, n)，
+// 生产者可据此提前暂停和恢复接收。fn可为nil，在持有队列锁时同步调用，不能阻塞也不能调用队列接口；high<=0表示关闭
+func (q *SyncQueue) SetWatermarks(high, low int, fn func(high bool, n int)) *SyncQueue {
+	q.wm.Set(high, low, fn)
+	return q
+}
+
+// SubscribeWatermarks 返回的channel在队列长度涨到高水位时收到true，回落到低水位时收到false，
+// 读取不及时只保留最新状态，调用返回的函数取消订阅并关闭channel
+func (q *SyncQueue) SubscribeWatermarks() (<-chan bool, func()) {
+	return q.wm.Subscribe()
+}
+
+// WaitEmpty 阻塞直到队列为空或ctx取消，返回ctx的错误
+func (q *SyncQueue) WaitEmpty(ctx context.Context) error {
+	return q.empty.WaitBelow(ctx)
+}
+
+// SubscribeEmpty 返回的channel在队列变为空时收到true，变为非空时收到false，
+// 读取不及时只保留最新状态，调用返回的函数取消订阅并关闭channel
+func (q *SyncQueue) SubscribeEmpty() (<-chan bool, func()) {
+	return q.empty.SubscribeBelow()
+}
+
+// Len 返回队列中元素个数
+func (q *SyncQueue) Len() int {
+	var n int
+	withLock(q.cond.L, func() {
+		n = q.l.Len()
+	})
+	return n
+}
+
 func (q *SyncQueue) Destroy() {
 	// cancel enqueue/dequeue goroutine
 	q.cancel()
+	// wake up DequeueContext waiting
+	withLock(q.cond.L, q.cond.Broadcast)
+	if l := q.logger.Load(); l != nil {
+		if n := q.Len(); n > 0 {
+			l.Warn("queue destroyed with elements left", slog.Int("len", n))
+		} else {
+			l.Debug("queue destroyed")
+		}
+	}
 }
 
 func withLock(lk sync.Locker, fn func()) {
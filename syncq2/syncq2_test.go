@@ -1,10 +1,16 @@
 package syncq2
 
 import (
+	"bytes"
+	"context"
 	"errors"
+	"log/slog"
+	"strings"
 	"sync"
 	"testing"
 	"time"
+
+	"github.com/luweimy/goutil/observer"
 )
 
 func TestSyncQueue(t *testing.T) {
@@ -109,3 +115,155 @@ func BenchmarkSyncQueue2(b *testing.B) {
 
 	wg.Wait()
 }
+
+func TestSyncQueue_DequeueContext(t *testing.T) {
+	q := New()
+	q.Enqueue(1)
+	if v, err := q.DequeueContext(context.Background()); err != nil || v != 1 {
+		t.Errorf("except 1, actual %v %v", v, err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond*100)
+	defer cancel()
+	if _, err := q.DequeueContext(ctx); err != context.DeadlineExceeded {
+		t.Errorf("except %v, actual %v", context.DeadlineExceeded, err)
+	}
+
+	go func() {
+		time.Sleep(time.Millisecond * 100)
+		q.Destroy()
+	}()
+	if _, err := q.DequeueContext(context.Background()); err != ErrDestroyed {
+		t.Errorf("except %v, actual %v", ErrDestroyed, err)
+	}
+}
+
+type recordObserver struct {
+	observer.Nop
+	enqueued, dequeued int
+	wait               time.Duration
+}
+
+func (o *recordObserver) OnEnqueue(e observer.QueueEvent) { o.enqueued++ }
+func (o *recordObserver) OnDequeue(e observer.QueueEvent) { o.dequeued++; o.wait += e.Wait }
+
+func TestNewWithObserver(t *testing.T) {
+	obs := &recordObserver{}
+	q := NewWithObserver(obs)
+	q.Enqueue(1)
+	q.Enqueue(2)
+	time.Sleep(time.Millisecond * 10)
+	if v := q.Dequeue(); v != 1 {
+		t.Errorf("except 1, actual %v", v)
+	}
+	if v, _ := q.DequeueContext(context.Background()); v != 2 {
+		t.Errorf("except 2, actual %v", v)
+	}
+	if obs.enqueued != 2 || obs.dequeued != 2 || obs.wait < time.Millisecond*20 {
+		t.Errorf("observer not except, %+v", obs)
+	}
+}
+
+func TestSyncQueue_SetLogger(t *testing.T) {
+	buf := &bytes.Buffer{}
+	q := New().SetLogger(slog.New(slog.NewTextHandler(buf, nil)))
+	q.Enqueue(1)
+	q.Destroy()
+
+	if s := `level=WARN msg="queue destroyed with elements left" len=1`; !strings.Contains(buf.String(), s) {
+		t.Errorf("except %q in log: %s", s, buf.String())
+	}
+}
+
+func TestSyncQueue_DequeueWithMeta(t *testing.T) {
+	for _, q := range []*SyncQueue{New(), NewWithObserver(&recordObserver{})} {
+		q.EnqueueWithMeta(1, map[string]string{"k": "v"})
+		q.Enqueue(2)
+		v, meta, err := q.DequeueWithMeta(context.Background())
+		if err != nil || v != 1 || meta["k"] != "v" {
+			t.Errorf("except 1 with meta, actual %v %v %v", v, meta, err)
+		}
+		v, meta, err = q.DequeueWithMeta(context.Background())
+		if err != nil || v != 2 || meta != nil {
+			t.Errorf("except 2 without meta, actual %v %v %v", v, meta, err)
+		}
+	}
+}
+
+func TestSyncQueue_SetWatermarks(t *testing.T) {
+	var events []bool
+	q := New().SetWatermarks(3, 1, func(high bool, n int) {
+		events = append(events, high)
+	})
+	defer q.Destroy()
+	ch, unsubscribe := q.SubscribeWatermarks()
+
+	for i := 0; i < 4; i++ {
+		q.Enqueue(i)
+	}
+	q.Dequeue()
+	q.Dequeue()
+	q.Dequeue()
+	if len(events) != 2 || !events[0] || events[1] {
+		t.Errorf("except [true false], actual %v", events)
+	}
+	if v := <-ch; v {
+		t.Error("except subscriber receives the latest state false")
+	}
+	unsubscribe()
+	if _, ok := <-ch; ok {
+		t.Error("except channel closed")
+	}
+}
+
+func TestSyncQueue_WaitEmpty(t *testing.T) {
+	q := New()
+	defer q.Destroy()
+	if err := q.WaitEmpty(context.Background()); err != nil {
+		t.Errorf("except nil, actual %v", err)
+	}
+	ch, unsubscribe := q.SubscribeEmpty()
+	defer unsubscribe()
+
+	q.Enqueue(1)
+	if v := <-ch; v {
+		t.Error("except non-empty transition")
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond*10)
+	defer cancel()
+	if err := q.WaitEmpty(ctx); err != context.DeadlineExceeded {
+		t.Errorf("except %v, actual %v", context.DeadlineExceeded, err)
+	}
+
+	go q.Dequeue()
+	if err := q.WaitEmpty(context.Background()); err != nil || q.Len() != 0 {
+		t.Errorf("except empty, actual %v %d", err, q.Len())
+	}
+	if v := <-ch; !v {
+		t.Error("except empty transition")
+	}
+}
+
+func TestSyncQueue_TryDequeue(t *testing.T) {
+	q := New()
+	defer q.Destroy()
+	if _, ok := q.TryDequeue(); ok {
+		t.Error("except false on empty queue")
+	}
+	q.Enqueue(2)
+	q.Enqueue(3)
+	q.EnqueueFront(1)
+	if v, ok := q.TryDequeueBack(); !ok || v != 3 {
+		t.Errorf("except 3, actual %v", v)
+	}
+	if v, ok := q.TryDequeue(); !ok || v != 1 {
+		t.Errorf("except 1, actual %v", v)
+	}
+	q.Enqueue(4)
+	if n := q.Clear(); n != 2 || q.Len() != 0 {
+		t.Errorf("except 2 cleared, actual %d len %d", n, q.Len())
+	}
+	if _, ok := q.TryDequeueBack(); ok {
+		t.Error("except false on empty queue")
+	}
+}
@@ -0,0 +1,150 @@
+package workerq
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/luweimy/goutil/syncq2"
+)
+
+// ErrBatcherClosed is the error of items submitted after Batcher closed.
+var ErrBatcherClosed = errors.New("workerq: batcher closed")
+
+// BatchFunc process a batch of items, each item can be resolved or rejected individually,
+// items not settled by BatchFunc are settled with the error of the batch worker.
+type BatchFunc func(worker *Worker, items []*BatchItem) error
+
+// BatchItem is a handle of the value submitted to Batcher.
+type BatchItem struct {
+	Value interface{}
+
+	once   sync.Once
+	done   chan struct{}
+	result interface{}
+	err    error
+}
+
+func newBatchItem(value interface{}) *BatchItem {
+	return &BatchItem{
+		Value: value,
+		done:  make(chan struct{}),
+	}
+}
+
+// Resolve settle the item with result, only the first settlement takes effect.
+func (i *BatchItem) Resolve(result interface{}) {
+	i.settle(result, nil)
+}
+
+// Reject settle the item with err, only the first settlement takes effect.
+func (i *BatchItem) Reject(err error) {
+	i.settle(nil, err)
+}
+
+func (i *BatchItem) settle(result interface{}, err error) {
+	i.once.Do(func() {
+		i.result, i.err = result, err
+		close(i.done)
+	})
+}
+
+// Done is closed when the item is settled.
+func (i *BatchItem) Done() <-chan struct{} {
+	return i.done
+}
+
+// Wait block until the item is settled, return its result and error.
+func (i *BatchItem) Wait() (interface{}, error) {
+	<-i.done
+	return i.result, i.err
+}
+
+// Batcher collect submitted items until maxSize items are collected or maxWait elapsed
+// since the first item of batch, then add one worker to process the batch.
+type Batcher struct {
+	q       *WorkerQueue
+	items   *syncq2.SyncQueue
+	maxSize int
+	maxWait time.Duration
+	fn      BatchFunc
+
+	ctx    context.Context
+	cancel context.CancelFunc
+	done   chan struct{} // closed when collecting goroutine exit
+	mu     sync.RWMutex  // rlock during submitting, in case of submit after close
+}
+
+// NewBatcher create Batcher process batches by q, maxSize <= 0 means unlimited batch size.
+func NewBatcher(q *WorkerQueue, maxSize int, maxWait time.Duration, fn BatchFunc) *Batcher {
+	ctx, cancel := context.WithCancel(context.Background())
+	b := &Batcher{
+		q:       q,
+		items:   syncq2.New(),
+		maxSize: maxSize,
+		maxWait: maxWait,
+		fn:      fn,
+		ctx:     ctx,
+		cancel:  cancel,
+		done:    make(chan struct{}),
+	}
+	go b.collect()
+	return b
+}
+
+// Submit add value to the batch being collected.
+func (b *Batcher) Submit(value interface{}) *BatchItem {
+	item := newBatchItem(value)
+	withLock(b.mu.RLocker(), func() {
+		if b.ctx.Err() != nil {
+			item.Reject(ErrBatcherClosed)
+			return
+		}
+		b.items.Enqueue(item)
+	})
+	return item
+}
+
+// Close stop collecting and flush the submitted items, it blocks until all batches added to queue.
+func (b *Batcher) Close() {
+	withLock(&b.mu, b.cancel)
+	<-b.done
+	b.items.Destroy()
+}
+
+func (b *Batcher) collect() {
+	defer close(b.done)
+	for {
+		// wait the first item of batch, dequeue without blocking after closed.
+		v, err := b.items.DequeueContext(b.ctx)
+		if err != nil {
+			return
+		}
+		batch := []*BatchItem{v.(*BatchItem)}
+
+		ctx, cancel := context.WithTimeout(b.ctx, b.maxWait)
+		for b.maxSize <= 0 || len(batch) < b.maxSize {
+			v, err := b.items.DequeueContext(ctx)
+			if err != nil {
+				break
+			}
+			batch = append(batch, v.(*BatchItem))
+		}
+		cancel()
+		b.dispatch(batch)
+	}
+}
+
+func (b *Batcher) dispatch(batch []*BatchItem) {
+	worker := b.q.AddWorkerFunc(nil, func(worker *Worker) error {
+		return b.fn(worker, batch)
+	})
+	go func() {
+		<-worker.Done()
+		err := workerErr(worker)
+		for _, item := range batch {
+			item.settle(nil, err)
+		}
+	}()
+}
@@ -0,0 +1,72 @@
+package workerq
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestBatcher(t *testing.T) {
+	wq := New(2).Start()
+	defer wq.Stop()
+
+	var (
+		mu    sync.Mutex
+		sizes []int
+	)
+	errOdd := errors.New("odd")
+	b := NewBatcher(wq, 3, time.Millisecond*100, func(worker *Worker, items []*BatchItem) error {
+		withLock(&mu, func() {
+			sizes = append(sizes, len(items))
+		})
+		for _, item := range items {
+			if v := item.Value.(int); v%2 == 0 {
+				item.Resolve(v * 10)
+			} else {
+				item.Reject(errOdd)
+			}
+		}
+		return nil
+	})
+
+	items := make([]*BatchItem, 0, 4)
+	for i := 0; i < 4; i++ {
+		items = append(items, b.Submit(i))
+	}
+	for i, item := range items {
+		result, err := item.Wait()
+		if i%2 == 0 && (result != i*10 || err != nil) {
+			t.Errorf("except %v, actual %v %v", i*10, result, err)
+		}
+		if i%2 == 1 && err != errOdd {
+			t.Errorf("except %v, actual %v", errOdd, err)
+		}
+	}
+
+	// first batch is full, the second is flushed by maxWait.
+	withLock(&mu, func() {
+		if len(sizes) != 2 || sizes[0] != 3 || sizes[1] != 1 {
+			t.Errorf("except batches [3 1], actual %v", sizes)
+		}
+	})
+
+	b.Close()
+	if _, err := b.Submit(5).Wait(); err != ErrBatcherClosed {
+		t.Errorf("except %v, actual %v", ErrBatcherClosed, err)
+	}
+}
+
+func TestBatcher_Panic(t *testing.T) {
+	wq := New(1).Start()
+	defer wq.Stop()
+
+	b := NewBatcher(wq, 0, time.Millisecond*10, func(worker *Worker, items []*BatchItem) error {
+		panic("batch panic")
+	})
+	defer b.Close()
+
+	if _, err := b.Submit(1).Wait(); err == nil {
+		t.Error("except panic error")
+	}
+}
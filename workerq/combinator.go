@@ -0,0 +1,116 @@
+package workerq
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"time"
+
+	"go.uber.org/multierr"
+)
+
+// ErrNoWorkers is returned by WaitAny and Race without workers.
+var ErrNoWorkers = errors.New("workerq: no workers")
+
+// Cancel make worker done with context.Canceled, it is no-op if worker is done already.
+// queued worker is never processed, the processing WorkerFunc should watch worker.Done() to stop in time.
+func (c *Worker) Cancel() {
+	c.abort(context.Canceled)
+}
+
+// WaitAll wait all workers done, return combined errors of workers.
+// if ctx is done before, ctx.Err() is combined too.
+func WaitAll(ctx context.Context, workers ...*Worker) error {
+	for _, worker := range workers {
+		select {
+		case <-worker.Done():
+		case <-ctx.Done():
+			return multierr.Append(workersErr(workers), ctx.Err())
+		}
+	}
+	return workersErr(workers)
+}
+
+// WaitAny wait any of workers done, return the worker and its error.
+func WaitAny(ctx context.Context, workers ...*Worker) (*Worker, error) {
+	if len(workers) == 0 {
+		return nil, ErrNoWorkers
+	}
+	done := notifyDone(ctx, workers)
+	select {
+	case worker := <-done:
+		return worker, workerErr(worker)
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// Race wait the first worker succeeded and cancel the rest,
+// return nil and combined errors of workers if all workers failed.
+func Race(ctx context.Context, workers ...*Worker) (*Worker, error) {
+	if len(workers) == 0 {
+		return nil, ErrNoWorkers
+	}
+	done := notifyDone(ctx, workers)
+	for range workers {
+		select {
+		case worker := <-done:
+			if workerErr(worker) != nil {
+				continue
+			}
+			for _, w := range workers {
+				if w != worker {
+					w.Cancel()
+				}
+			}
+			return worker, nil
+		case <-ctx.Done():
+			return nil, multierr.Append(workersErr(workers), ctx.Err())
+		}
+	}
+	return nil, workersErr(workers)
+}
+
+// WithTimeout make worker done with context.DeadlineExceeded if it is not done in timeout.
+func WithTimeout(worker *Worker, timeout time.Duration) *Worker {
+	timer := time.AfterFunc(timeout, func() {
+		worker.abort(context.DeadlineExceeded)
+	})
+	go func() {
+		<-worker.Done()
+		timer.Stop()
+	}()
+	return worker
+}
+
+// notifyDone deliver workers to the returned channel once done.
+func notifyDone(ctx context.Context, workers []*Worker) <-chan *Worker {
+	done := make(chan *Worker, len(workers))
+	for _, worker := range workers {
+		go func(worker *Worker) {
+			select {
+			case <-worker.Done():
+				done <- worker
+			case <-ctx.Done():
+			}
+		}(worker)
+	}
+	return done
+}
+
+// workerErr return error of worker, worker canceled before finished returns the cancellation error.
+func workerErr(worker *Worker) error {
+	err := worker.Err()
+	if err == nil && atomic.LoadInt32(&worker.finished) == 0 {
+		err = worker.ctx.Err()
+	}
+	return err
+}
+
+func workersErr(workers []*Worker) error {
+	var errs error
+	for _, worker := range workers {
+		errs = multierr.Append(errs, workerErr(worker))
+	}
+	return errs
+}
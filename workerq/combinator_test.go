@@ -0,0 +1,167 @@
+package workerq
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"go.uber.org/multierr"
+
+	"github.com/luweimy/goutil/observer"
+)
+
+func TestWaitAll(t *testing.T) {
+	wq := New(2).Start()
+	defer wq.Stop()
+
+	errFailed := errors.New("failed")
+	worker1 := wq.AddWorkerFunc(nil, func(worker *Worker) error {
+		time.Sleep(time.Millisecond * 100)
+		return nil
+	})
+	worker2 := wq.AddWorkerFunc(nil, func(worker *Worker) error {
+		return errFailed
+	})
+	if err := WaitAll(context.Background(), worker1, worker2); err != errFailed {
+		t.Errorf("except %v, actual %v", errFailed, err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond*100)
+	defer cancel()
+	worker3 := wq.AddWorkerFunc(nil, func(worker *Worker) error {
+		<-worker.Done()
+		return nil
+	})
+	if err := WaitAll(ctx, worker3); err != context.DeadlineExceeded {
+		t.Errorf("except %v, actual %v", context.DeadlineExceeded, err)
+	}
+	worker3.Cancel()
+}
+
+func TestWaitAny(t *testing.T) {
+	wq := New(2).Start()
+	defer wq.Stop()
+
+	worker1 := wq.AddWorkerFunc(nil, func(worker *Worker) error {
+		time.Sleep(time.Millisecond * 200)
+		return nil
+	})
+	worker2 := wq.AddWorkerFunc(nil, func(worker *Worker) error {
+		return nil
+	})
+	worker, err := WaitAny(context.Background(), worker1, worker2)
+	if worker != worker2 || err != nil {
+		t.Errorf("except worker2 %v, actual %v %v", worker2.ID(), worker.ID(), err)
+	}
+	if _, err := WaitAny(context.Background()); err != ErrNoWorkers {
+		t.Errorf("except %v, actual %v", ErrNoWorkers, err)
+	}
+}
+
+func TestRace(t *testing.T) {
+	wq := New(3).Start()
+	defer wq.Stop()
+
+	errFailed := errors.New("failed")
+	failed := wq.AddWorkerFunc(nil, func(worker *Worker) error {
+		return errFailed
+	})
+	fast := wq.AddWorkerFunc(nil, func(worker *Worker) error {
+		time.Sleep(time.Millisecond * 50)
+		return nil
+	})
+	slow := wq.AddWorkerFunc(nil, func(worker *Worker) error {
+		select {
+		case <-time.After(time.Second):
+		case <-worker.Done():
+		}
+		return nil
+	})
+
+	worker, err := Race(context.Background(), failed, fast, slow)
+	if worker != fast || err != nil {
+		t.Errorf("except fast worker %v, actual %v %v", fast.ID(), worker, err)
+	}
+	if err := slow.Wait(); err != context.Canceled {
+		t.Errorf("except slow worker canceled, actual %v", err)
+	}
+
+	all := []*Worker{
+		wq.AddWorkerFunc(nil, func(worker *Worker) error { return errFailed }),
+		wq.AddWorkerFunc(nil, func(worker *Worker) error { return errFailed }),
+	}
+	if _, err := Race(context.Background(), all...); len(multierr.Errors(err)) != 2 {
+		t.Errorf("except 2 errors, actual %v", err)
+	}
+}
+
+func TestWithTimeout(t *testing.T) {
+	wq := New(1).Start()
+	defer wq.Stop()
+
+	worker := WithTimeout(wq.AddWorkerFunc(nil, func(worker *Worker) error {
+		<-worker.Done()
+		return nil
+	}), time.Millisecond*50)
+	if err := worker.Wait(); err != context.DeadlineExceeded {
+		t.Errorf("except %v, actual %v", context.DeadlineExceeded, err)
+	}
+}
+
+// slowFinishObserver delay finish of workers.
+type slowFinishObserver struct {
+	observer.Nop
+	d time.Duration
+}
+
+func (o slowFinishObserver) OnWorkerFinish(e observer.WorkerEvent) {
+	time.Sleep(o.d)
+}
+
+func TestWithTimeout_Finished(t *testing.T) {
+	wq := NewWithObserver(1, slowFinishObserver{d: time.Millisecond * 100}).Start()
+	defer wq.Stop()
+
+	// timeout fires after work returned but before worker done, the worker succeeded.
+	worker := WithTimeout(wq.AddWorkerFunc(nil, func(worker *Worker) error {
+		return nil
+	}), time.Millisecond*50)
+	if err := worker.Wait(); err != nil {
+		t.Errorf("except nil, actual %v", err)
+	}
+}
+
+func TestRace_QueuedLoser(t *testing.T) {
+	wq := New(1).Start()
+	defer wq.Stop()
+	busy := New(1).Start()
+	defer busy.Stop()
+
+	release := make(chan struct{})
+	busy.AddWorkerFunc(nil, func(worker *Worker) error {
+		<-release
+		return nil
+	})
+	var ran int32
+	loser := busy.AddWorkerFunc(nil, func(worker *Worker) error {
+		atomic.AddInt32(&ran, 1)
+		return nil
+	})
+	winner := wq.AddWorkerFunc(nil, func(worker *Worker) error {
+		return nil
+	})
+	if worker, err := Race(context.Background(), winner, loser); worker != winner || err != nil {
+		t.Errorf("except winner %v, actual %v %v", winner.ID(), worker, err)
+	}
+	if err := loser.Wait(); err != context.Canceled {
+		t.Errorf("except %v, actual %v", context.Canceled, err)
+	}
+
+	close(release)
+	busy.WaitIdle(context.Background())
+	if n := atomic.LoadInt32(&ran); n != 0 {
+		t.Errorf("except loser never run, actual run %d times", n)
+	}
+}
@@ -0,0 +1,120 @@
+package workerq
+
+import (
+	"context"
+	"errors"
+	"iter"
+	"sync"
+
+	"github.com/luweimy/goutil/syncq2"
+)
+
+// ErrNoPendingWorkers is returned when all workers of CompletionService have been received.
+var ErrNoPendingWorkers = errors.New("workerq: no pending workers")
+
+// CompletionService add workers to WorkerQueue and receive them in completion order,
+// rather than waiting them one by one in submission order.
+type CompletionService struct {
+	q    *WorkerQueue
+	done *syncq2.SyncQueue // done workers in completion order
+
+	mu      sync.Mutex
+	pending int // num of workers added but not received
+}
+
+// Completions create a CompletionService submit workers to the queue.
+func (q *WorkerQueue) Completions() *CompletionService {
+	return &CompletionService{
+		q:    q,
+		done: syncq2.New(),
+	}
+}
+
+// AddWorker add worker to the queue, it will be received once done.
+func (s *CompletionService) AddWorker(worker *Worker) *Worker {
+	withLock(&s.mu, func() {
+		s.pending++
+	})
+	s.q.AddWorker(worker)
+	go func() {
+		<-worker.Done()
+		s.done.Enqueue(worker)
+	}()
+	return worker
+}
+
+func (s *CompletionService) AddWorkerFunc(ctx context.Context, wf WorkerFunc) *Worker {
+	return s.AddWorker(NewWorker(ctx, wf))
+}
+
+// Pending return num of workers added but not received.
+func (s *CompletionService) Pending() int {
+	var n int
+	withLock(&s.mu, func() {
+		n = s.pending
+	})
+	return n
+}
+
+// Next block until a worker done and return it,
+// return ErrNoPendingWorkers if all added workers have been received.
+func (s *CompletionService) Next(ctx context.Context) (*Worker, error) {
+	var err error
+	withLock(&s.mu, func() {
+		if s.pending <= 0 {
+			err = ErrNoPendingWorkers
+			return
+		}
+		s.pending-- // reserve one, in case of concurrent Next
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	v, err := s.done.DequeueContext(ctx)
+	if err != nil {
+		withLock(&s.mu, func() {
+			s.pending++
+		})
+		return nil, err
+	}
+	return v.(*Worker), nil
+}
+
+// All iterate workers in completion order, until all added workers have been received.
+func (s *CompletionService) All() iter.Seq[*Worker] {
+	return func(yield func(*Worker) bool) {
+		for {
+			worker, err := s.Next(context.Background())
+			if err != nil || !yield(worker) {
+				return
+			}
+		}
+	}
+}
+
+// C return a channel deliver workers in completion order,
+// it will be closed when all added workers have been received or ctx is done.
+func (s *CompletionService) C(ctx context.Context) <-chan *Worker {
+	ch := make(chan *Worker)
+	go func() {
+		defer close(ch)
+		for {
+			worker, err := s.Next(ctx)
+			if err != nil {
+				return
+			}
+			select {
+			case ch <- worker:
+			case <-ctx.Done():
+				// give back the worker to the service.
+				withLock(&s.mu, func() {
+					s.pending++
+				})
+				s.done.Enqueue(worker)
+				return
+			}
+		}
+	}()
+	return ch
+}
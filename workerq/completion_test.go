@@ -0,0 +1,65 @@
+package workerq
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestWorkerQueue_Completions(t *testing.T) {
+	wq := New(3).Start()
+	defer wq.Stop()
+
+	cs := wq.Completions()
+	sleep := func(d time.Duration) WorkerFunc {
+		return func(worker *Worker) error {
+			time.Sleep(d)
+			return nil
+		}
+	}
+	worker1 := cs.AddWorkerFunc(nil, sleep(time.Millisecond*300))
+	worker2 := cs.AddWorkerFunc(nil, sleep(time.Millisecond*100))
+	worker3 := cs.AddWorkerFunc(nil, sleep(time.Millisecond*200))
+
+	except := []*Worker{worker2, worker3, worker1}
+	i := 0
+	for worker := range cs.All() {
+		if worker != except[i] {
+			t.Errorf("except worker %v, actual %v", except[i].ID(), worker.ID())
+		}
+		i++
+	}
+	if i != len(except) {
+		t.Errorf("except %v workers, actual %v", len(except), i)
+	}
+	if _, err := cs.Next(context.Background()); err != ErrNoPendingWorkers {
+		t.Errorf("except %v, actual %v", ErrNoPendingWorkers, err)
+	}
+}
+
+func TestCompletionService_C(t *testing.T) {
+	wq := New(2).Start()
+	defer wq.Stop()
+
+	cs := wq.Completions()
+	release := make(chan struct{})
+	worker1 := cs.AddWorkerFunc(nil, func(worker *Worker) error {
+		<-release
+		return nil
+	})
+	worker2 := cs.AddWorkerFunc(nil, func(worker *Worker) error {
+		return nil
+	})
+
+	ch := cs.C(context.Background())
+	if worker := <-ch; worker != worker2 {
+		t.Errorf("except worker2 %v, actual %v", worker2.ID(), worker.ID())
+	}
+	close(release)
+	if worker := <-ch; worker != worker1 {
+		t.Errorf("except worker1 %v, actual %v", worker1.ID(), worker.ID())
+	}
+	if _, ok := <-ch; ok {
+		t.Error("except channel closed")
+	}
+}
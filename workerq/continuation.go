@@ -0,0 +1,98 @@
+package workerq
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+
+	"go.uber.org/multierr"
+)
+
+// ErrContinuationSkipped is the error of continuation which condition is not satisfied,
+// e.g. OnSuccess continuation of a failed worker.
+var ErrContinuationSkipped = errors.New("workerq: continuation skipped")
+
+// ContinuationFunc is called with the finished parent worker,
+// parent.Result() and parent.Err() can be used to pass along result.
+type ContinuationFunc func(parent *Worker, worker *Worker) error
+
+// Then add a continuation worker to q once the worker finished, whatever it succeeded or failed.
+// if q is nil, the continuation is added to the same queue as the worker.
+// if the worker is canceled before finished, the continuation is canceled too.
+func (c *Worker) Then(q *WorkerQueue, fn ContinuationFunc) *Worker {
+	return c.continueWith(q, fn, func(parent *Worker) bool {
+		return true
+	})
+}
+
+// OnSuccess is like Then, but the continuation only run when the worker succeeded,
+// otherwise it is done with ErrContinuationSkipped.
+func (c *Worker) OnSuccess(q *WorkerQueue, fn ContinuationFunc) *Worker {
+	return c.continueWith(q, fn, func(parent *Worker) bool {
+		return parent.Err() == nil
+	})
+}
+
+// OnFailure is like Then, but the continuation only run when the worker failed,
+// otherwise it is done with ErrContinuationSkipped.
+func (c *Worker) OnFailure(q *WorkerQueue, fn ContinuationFunc) *Worker {
+	return c.continueWith(q, fn, func(parent *Worker) bool {
+		return parent.Err() != nil
+	})
+}
+
+func (c *Worker) continueWith(q *WorkerQueue, fn ContinuationFunc, cond func(parent *Worker) bool) *Worker {
+	// continuation carries values of parent context, and is aborted with the parent cancellation.
+	next := NewWorker(context.WithoutCancel(c.base), func(worker *Worker) error {
+		return fn(c, worker)
+	})
+	go func() {
+		<-c.Done()
+		if atomic.LoadInt32(&c.finished) == 0 {
+			// parent is canceled, not finished.
+			next.abort(c.ctx.Err())
+			return
+		}
+		stop := context.AfterFunc(c.base, func() {
+			next.abort(c.base.Err())
+		})
+		defer func() {
+			<-next.Done()
+			stop()
+		}()
+		if !cond(c) {
+			next.abort(ErrContinuationSkipped)
+			return
+		}
+		if q == nil {
+			q = c.q
+		}
+		if q == nil {
+			// parent is not processed by queue.
+			go next.Do()
+			return
+		}
+		q.AddWorker(next)
+	}()
+	return next
+}
+
+// abort make worker done with err, it is no-op if worker is done already or its work returned.
+func (c *Worker) abort(err error) {
+	aborted := false
+	withLock(&c.mu, func() {
+		// finished is set under mu, so err is never added to a worker whose work returned.
+		if aborted = c.ctx.Err() == nil && atomic.LoadInt32(&c.finished) == 0; aborted {
+			c.errs = multierr.Append(c.errs, err)
+		}
+	})
+	if !aborted {
+		return
+	}
+	c.progress.close()
+	c.cancel()
+	if c.q != nil {
+		// worker canceled while queued is dropped, the dispatcher skips it.
+		c.q.dropQueued(c)
+	}
+}
@@ -0,0 +1,77 @@
+package workerq
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestWorker_Then(t *testing.T) {
+	wq := New(2).Start()
+	defer wq.Stop()
+
+	parent := wq.AddWorkerFunc(nil, func(worker *Worker) error {
+		worker.SetResult(1)
+		return nil
+	})
+	next := parent.Then(nil, func(parent *Worker, worker *Worker) error {
+		worker.SetResult(parent.Result().(int) + 1)
+		return nil
+	})
+	if err := next.Wait(); err != nil {
+		t.Error(err)
+	}
+	if result := next.Result(); result != 2 {
+		t.Errorf("except 2, actual %v", result)
+	}
+}
+
+func TestWorker_OnSuccessOnFailure(t *testing.T) {
+	wq := New(2).Start()
+	defer wq.Stop()
+
+	errFailed := errors.New("failed")
+	parent := wq.AddWorkerFunc(nil, func(worker *Worker) error {
+		return errFailed
+	})
+	success := parent.OnSuccess(nil, func(parent *Worker, worker *Worker) error {
+		t.Error("OnSuccess continuation of failed worker is called")
+		return nil
+	})
+	failure := parent.OnFailure(wq, func(parent *Worker, worker *Worker) error {
+		worker.SetResult(parent.Err())
+		return nil
+	})
+
+	if err := success.Wait(); err != ErrContinuationSkipped {
+		t.Errorf("except %v, actual %v", ErrContinuationSkipped, err)
+	}
+	if err := failure.Wait(); err != nil {
+		t.Error(err)
+	}
+	if result := failure.Result(); result != errFailed {
+		t.Errorf("except %v, actual %v", errFailed, result)
+	}
+}
+
+func TestWorker_ThenCanceled(t *testing.T) {
+	wq := New(1).Start()
+	defer wq.Stop()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	parent := wq.AddWorkerFunc(ctx, func(worker *Worker) error {
+		time.Sleep(time.Millisecond * 100)
+		return nil
+	})
+	next := parent.Then(nil, func(parent *Worker, worker *Worker) error {
+		t.Error("continuation of canceled worker is called")
+		return nil
+	})
+
+	<-parent.Begin()
+	cancel()
+	if err := next.Wait(); err != context.Canceled {
+		t.Errorf("except %v, actual %v", context.Canceled, err)
+	}
+}
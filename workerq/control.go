@@ -0,0 +1,173 @@
+package workerq
+
+import (
+	"context"
+	"log/slog"
+	"sort"
+	"time"
+)
+
+// maxFailures is num of recent failures kept by queue.
+const maxFailures = 32
+
+// Failure is a worker finished with error.
+type Failure struct {
+	WorkerID uint64
+	Name     string
+	Err      error
+	Time     time.Time
+	Run      time.Duration
+}
+
+// WorkerState is state of worker in queue.
+type WorkerState string
+
+const (
+	WorkerQueued  WorkerState = "queued"
+	WorkerRunning WorkerState = "running"
+)
+
+// WorkerInfo is snapshot of a worker in queue.
+type WorkerInfo struct {
+	ID            uint64
+	Name          string
+	State         WorkerState
+	EnqueuedAt    time.Time
+	StartedAt     time.Time // zero if queued
+	LastHeartbeat time.Time
+	Progress      Progress
+}
+
+// Pause stop dispatching backlog workers, processing workers are not affected.
+func (q *WorkerQueue) Pause() {
+	withLock(&q.runningMu, func() {
+		if q.resume == nil {
+			q.resume = make(chan struct{})
+			q.log(slog.LevelInfo, "queue paused")
+		}
+	})
+}
+
+// Resume continue dispatching backlog workers.
+func (q *WorkerQueue) Resume() {
+	withLock(&q.runningMu, func() {
+		if q.resume != nil {
+			close(q.resume)
+			q.resume = nil
+			q.log(slog.LevelInfo, "queue resumed")
+		}
+	})
+}
+
+func (q *WorkerQueue) Paused() bool {
+	var paused bool
+	withLock(&q.runningMu, func() {
+		paused = q.resume != nil
+	})
+	return paused
+}
+
+// waitResumed block while paused, return false if queue stopped.
+func (q *WorkerQueue) waitResumed() bool {
+	var resume chan struct{}
+	withLock(&q.runningMu, func() {
+		resume = q.resume
+	})
+	if resume == nil {
+		return true
+	}
+	select {
+	case <-resume:
+		return true
+	case <-q.ctx.Done():
+		return false
+	}
+}
+
+// WaitIdle block until backlog is empty and no worker is processing, or ctx done.
+// after Stop, it returns once processing workers done, workers left in backlog are ignored.
+func (q *WorkerQueue) WaitIdle(ctx context.Context) error {
+	return q.idle.WaitBelow(ctx)
+}
+
+// SubscribeIdle return channel receives true when queue becomes idle, false when a worker added to idle queue.
+// slow subscriber only receives the latest state, the channel will be closed when unsubscribe func called.
+func (q *WorkerQueue) SubscribeIdle() (<-chan bool, func()) {
+	return q.idle.SubscribeBelow()
+}
+
+// CancelWorker cancel the queued or running worker by id, return false if not found.
+// queued worker is removed from queue and never processed.
+func (q *WorkerQueue) CancelWorker(id uint64) bool {
+	var worker *Worker
+	withLock(&q.runningMu, func() {
+		if worker = q.running[id]; worker == nil {
+			worker = q.queued[id]
+		}
+	})
+	if worker == nil {
+		return false
+	}
+	worker.Cancel()
+	return true
+}
+
+// Workers return snapshot of queued and running workers order by id.
+func (q *WorkerQueue) Workers() []WorkerInfo {
+	var infos []WorkerInfo
+	withLock(&q.runningMu, func() {
+		infos = make([]WorkerInfo, 0, len(q.queued)+len(q.running))
+		for _, worker := range q.queued {
+			infos = append(infos, worker.info(WorkerQueued))
+		}
+		for _, worker := range q.running {
+			infos = append(infos, worker.info(WorkerRunning))
+		}
+	})
+	sort.Slice(infos, func(i, j int) bool {
+		return infos[i].ID < infos[j].ID
+	})
+	return infos
+}
+
+// RecentFailures return recent failures of workers, the oldest first.
+func (q *WorkerQueue) RecentFailures() []Failure {
+	var failures []Failure
+	withLock(&q.runningMu, func() {
+		failures = make([]Failure, 0, len(q.failures))
+		failures = append(failures, q.failures[q.failureAt:]...)
+		failures = append(failures, q.failures[:q.failureAt]...)
+	})
+	return failures
+}
+
+func (q *WorkerQueue) addFailure(worker *Worker, err error, run time.Duration) {
+	f := Failure{
+		WorkerID: worker.id,
+		Name:     worker.name,
+		Err:      err,
+		Time:     time.Now(),
+		Run:      run,
+	}
+	withLock(&q.runningMu, func() {
+		if len(q.failures) < maxFailures {
+			q.failures = append(q.failures, f)
+			return
+		}
+		q.failures[q.failureAt] = f
+		q.failureAt = (q.failureAt + 1) % maxFailures
+	})
+}
+
+// info must be called with runningMu held.
+func (c *Worker) info(state WorkerState) WorkerInfo {
+	return WorkerInfo{
+		ID:            c.id,
+		Name:          c.name,
+		State:         state,
+		EnqueuedAt:    c.enqueuedAt,
+		StartedAt:     c.startedAt,
+		LastHeartbeat: c.LastHeartbeat(),
+		Progress:      c.Progress(),
+	}
+}
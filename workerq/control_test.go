@@ -0,0 +1,142 @@
+package workerq
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestWorkerQueue_WaitIdle(t *testing.T) {
+	q := New(1).Start()
+	defer q.Stop()
+	if err := q.WaitIdle(context.Background()); err != nil {
+		t.Errorf("except nil, actual %v", err)
+	}
+	ch, unsubscribe := q.SubscribeIdle()
+	defer unsubscribe()
+
+	release := make(chan struct{})
+	q.AddWorkerFunc(nil, func(worker *Worker) error {
+		<-release
+		return nil
+	})
+	q.AddWorkerFunc(nil, nil)
+	if v := <-ch; v {
+		t.Error("except busy transition")
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond*10)
+	defer cancel()
+	if err := q.WaitIdle(ctx); err != context.DeadlineExceeded {
+		t.Errorf("except %v, actual %v", context.DeadlineExceeded, err)
+	}
+
+	close(release)
+	if err := q.WaitIdle(context.Background()); err != nil {
+		t.Errorf("except nil, actual %v", err)
+	}
+	if n := q.NumBacklogWorkers() + len(q.Workers()); n != 0 {
+		t.Errorf("except 0, actual %d", n)
+	}
+	if v := <-ch; !v {
+		t.Error("except idle transition")
+	}
+}
+
+func TestWorkerQueue_CancelQueued(t *testing.T) {
+	q := New(1).Start()
+	defer q.Stop()
+
+	release := make(chan struct{})
+	q.AddWorkerFunc(nil, func(worker *Worker) error {
+		<-release
+		return nil
+	})
+	var ran int32
+	queued := q.AddWorkerFunc(nil, func(worker *Worker) error {
+		atomic.AddInt32(&ran, 1)
+		return nil
+	})
+	if !q.CancelWorker(queued.ID()) {
+		t.Fatal("except worker found")
+	}
+	if err := queued.Wait(); err != context.Canceled {
+		t.Errorf("except %v, actual %v", context.Canceled, err)
+	}
+	for _, info := range q.Workers() {
+		if info.ID == queued.ID() {
+			t.Errorf("except canceled worker not listed, actual %v", info.State)
+		}
+	}
+
+	close(release)
+	if err := q.WaitIdle(context.Background()); err != nil {
+		t.Errorf("except nil, actual %v", err)
+	}
+	if n := atomic.LoadInt32(&ran); n != 0 {
+		t.Errorf("except 0, actual %d", n)
+	}
+}
+
+func TestWorkerQueue_WaitIdleStopped(t *testing.T) {
+	q := New(1).Start()
+	release := make(chan struct{})
+	started := make(chan struct{})
+	q.AddWorkerFunc(nil, func(worker *Worker) error {
+		close(started)
+		<-release
+		return nil
+	})
+	<-started
+	q.AddWorkerFunc(nil, nil) // left in backlog
+	q.Stop()
+	q.AddWorkerFunc(nil, nil) // added after stopped
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond*10)
+	defer cancel()
+	if err := q.WaitIdle(ctx); err != context.DeadlineExceeded {
+		t.Errorf("except %v, actual %v", context.DeadlineExceeded, err)
+	}
+	close(release)
+	ctx, cancel = context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	if err := q.WaitIdle(ctx); err != nil {
+		t.Errorf("except nil, actual %v", err)
+	}
+}
+
+func TestWorkerQueue_ContextDoneQueued(t *testing.T) {
+	q := New(1).Start()
+	defer q.Stop()
+
+	release := make(chan struct{})
+	q.AddWorkerFunc(nil, func(worker *Worker) error {
+		<-release
+		return nil
+	})
+	var ran int32
+	ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond*20)
+	defer cancel()
+	queued := q.AddWorkerFunc(ctx, func(worker *Worker) error {
+		atomic.AddInt32(&ran, 1)
+		return nil
+	})
+
+	// ctx of caller done while queued, the worker is dropped with ctx error.
+	if err := queued.Wait(); err != context.DeadlineExceeded {
+		t.Errorf("except %v, actual %v", context.DeadlineExceeded, err)
+	}
+	close(release)
+	if err := q.WaitIdle(context.Background()); err != nil {
+		t.Errorf("except nil, actual %v", err)
+	}
+	if n := atomic.LoadInt32(&ran); n != 0 {
+		t.Errorf("except 0, actual %d", n)
+	}
+	if err := queued.Err(); err != context.DeadlineExceeded {
+		t.Errorf("except %v, actual %v", context.DeadlineExceeded, err)
+	}
+	if err := WaitAll(context.Background(), queued); err != context.DeadlineExceeded {
+		t.Errorf("except %v, actual %v", context.DeadlineExceeded, err)
+	}
+}
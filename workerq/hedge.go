@@ -0,0 +1,140 @@
+package workerq
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+)
+
+const (
+	hedgeSamples    = 128 // num of recent durations to compute percentile
+	hedgeMinSamples = 16  // use HedgePolicy.Delay until samples are enough
+)
+
+// HedgePolicy configure hedged execution, it should only be used for idempotent workers.
+type HedgePolicy struct {
+	// Delay start a duplicate worker if not finished after the delay, <= 0 means never hedge.
+	Delay time.Duration
+	// Percentile(0, 1) use the percentile of recent hedged durations as the delay instead,
+	// Delay is used until there are enough samples, so no hedge before that if Delay <= 0.
+	Percentile float64
+	// MaxHedges is max num of duplicate workers, default 1.
+	MaxHedges int
+}
+
+// HedgeStats is statistics of hedged execution of the queue.
+type HedgeStats struct {
+	Requests  uint64 // num of hedged submissions
+	Hedges    uint64 // num of duplicate workers started
+	HedgeWins uint64 // num of submissions won by duplicate workers
+}
+
+type hedgeState struct {
+	mu        sync.Mutex
+	stats     HedgeStats
+	durations []time.Duration // ring of recent durations
+	next      int
+}
+
+// AddHedgedWorkerFunc add wf to the queue, and start duplicate workers if it is not finished in time.
+// the first succeeded worker wins, its result is set to the returned worker and the others are canceled.
+func (q *WorkerQueue) AddHedgedWorkerFunc(ctx context.Context, wf WorkerFunc, policy HedgePolicy) *Worker {
+	if policy.MaxHedges <= 0 {
+		policy.MaxHedges = 1
+	}
+	withLock(&q.hedge.mu, func() {
+		q.hedge.stats.Requests++
+	})
+
+	hedged := NewWorker(ctx, nil)
+	hedged.work = func(hedged *Worker) error {
+		// attempts are canceled once the hedged worker done.
+		start := time.Now()
+		attempts := []*Worker{q.AddWorkerFunc(hedged.Context(), wf)}
+		done := make(chan *Worker, policy.MaxHedges+1)
+		go notifyWorkerDone(attempts[0], done)
+
+		timer := time.NewTimer(time.Hour)
+		timer.Stop()
+		defer timer.Stop()
+		if d := q.hedgeDelay(policy); d > 0 {
+			timer.Reset(d)
+		}
+		for finished := 0; finished < len(attempts); {
+			select {
+			case worker := <-done:
+				finished++
+				if workerErr(worker) != nil {
+					continue
+				}
+				q.recordHedge(time.Since(start), worker != attempts[0])
+				hedged.SetResult(worker.Result())
+				return nil
+			case <-timer.C:
+				if len(attempts) > policy.MaxHedges {
+					continue
+				}
+				worker := q.AddWorkerFunc(hedged.Context(), wf)
+				attempts = append(attempts, worker)
+				go notifyWorkerDone(worker, done)
+				withLock(&q.hedge.mu, func() {
+					q.hedge.stats.Hedges++
+				})
+				if d := q.hedgeDelay(policy); d > 0 {
+					timer.Reset(d)
+				}
+			case <-hedged.Done():
+				return hedged.ctx.Err()
+			}
+		}
+		return workersErr(attempts)
+	}
+	go hedged.Do()
+	return hedged
+}
+
+// HedgeStats return statistics of hedged execution.
+func (q *WorkerQueue) HedgeStats() HedgeStats {
+	var stats HedgeStats
+	withLock(&q.hedge.mu, func() {
+		stats = q.hedge.stats
+	})
+	return stats
+}
+
+func (q *WorkerQueue) hedgeDelay(policy HedgePolicy) time.Duration {
+	if policy.Percentile <= 0 || policy.Percentile >= 1 {
+		return policy.Delay
+	}
+	var durations []time.Duration
+	withLock(&q.hedge.mu, func() {
+		durations = append(durations, q.hedge.durations...)
+	})
+	if len(durations) < hedgeMinSamples {
+		return policy.Delay
+	}
+	sort.Slice(durations, func(i, j int) bool {
+		return durations[i] < durations[j]
+	})
+	return durations[int(float64(len(durations)-1)*policy.Percentile)]
+}
+
+func (q *WorkerQueue) recordHedge(d time.Duration, hedgeWin bool) {
+	withLock(&q.hedge.mu, func() {
+		if hedgeWin {
+			q.hedge.stats.HedgeWins++
+		}
+		if len(q.hedge.durations) < hedgeSamples {
+			q.hedge.durations = append(q.hedge.durations, d)
+			return
+		}
+		q.hedge.durations[q.hedge.next] = d
+		q.hedge.next = (q.hedge.next + 1) % hedgeSamples
+	})
+}
+
+func notifyWorkerDone(worker *Worker, done chan<- *Worker) {
+	<-worker.Done()
+	done <- worker
+}
@@ -0,0 +1,89 @@
+package workerq
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestWorkerQueue_AddHedgedWorkerFunc(t *testing.T) {
+	wq := New(2).Start()
+	defer wq.Stop()
+
+	var (
+		calls    int32
+		canceled = make(chan struct{})
+	)
+	worker := wq.AddHedgedWorkerFunc(nil, func(worker *Worker) error {
+		if atomic.AddInt32(&calls, 1) == 1 {
+			// the original is slow, and canceled when duplicate wins.
+			select {
+			case <-worker.Done():
+				close(canceled)
+			case <-time.After(time.Second):
+			}
+			worker.SetResult("original")
+			return nil
+		}
+		worker.SetResult("hedge")
+		return nil
+	}, HedgePolicy{Delay: time.Millisecond * 50})
+
+	if err := worker.Wait(); err != nil {
+		t.Error(err)
+	}
+	if result := worker.Result(); result != "hedge" {
+		t.Errorf("except hedge, actual %v", result)
+	}
+	select {
+	case <-canceled:
+	case <-time.After(time.Millisecond * 500):
+		t.Error("original worker is not canceled")
+	}
+
+	stats := wq.HedgeStats()
+	if stats != (HedgeStats{Requests: 1, Hedges: 1, HedgeWins: 1}) {
+		t.Errorf("except 1 hedge win, actual %+v", stats)
+	}
+}
+
+func TestWorkerQueue_AddHedgedWorkerFuncNoHedge(t *testing.T) {
+	wq := New(2).Start()
+	defer wq.Stop()
+
+	worker := wq.AddHedgedWorkerFunc(context.Background(), func(worker *Worker) error {
+		worker.SetResult(1)
+		return nil
+	}, HedgePolicy{Delay: time.Second, Percentile: 0.95})
+	if err := worker.Wait(); err != nil || worker.Result() != 1 {
+		t.Errorf("except 1, actual %v %v", worker.Result(), err)
+	}
+	if stats := wq.HedgeStats(); stats.Hedges != 0 {
+		t.Errorf("except no hedges, actual %+v", stats)
+	}
+}
+
+func TestWorkerQueue_AddHedgedWorkerFuncZeroDelay(t *testing.T) {
+	wq := New(2).Start()
+	defer wq.Stop()
+
+	// zero policy and percentile without enough samples never hedge.
+	for _, policy := range []HedgePolicy{{}, {Percentile: 0.95}} {
+		var calls int32
+		worker := wq.AddHedgedWorkerFunc(nil, func(worker *Worker) error {
+			atomic.AddInt32(&calls, 1)
+			time.Sleep(time.Millisecond * 20)
+			return nil
+		}, policy)
+		if err := worker.Wait(); err != nil {
+			t.Error(err)
+		}
+		if n := atomic.LoadInt32(&calls); n != 1 {
+			t.Errorf("%+v: except 1 call, actual %d", policy, n)
+		}
+	}
+	if stats := wq.HedgeStats(); stats.Hedges != 0 {
+		t.Errorf("except no hedges, actual %+v", stats)
+	}
+}
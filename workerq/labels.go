@@ -0,0 +1,38 @@
+package workerq
+
+import (
+	"context"
+	"runtime/pprof"
+	"strconv"
+)
+
+// SetName set name of the queue, it is used as pprof label "workerq" of workers processed by the queue.
+// it should be called before the queue started.
+func (q *WorkerQueue) SetName(name string) *WorkerQueue {
+	q.name = name
+	return q
+}
+
+func (q *WorkerQueue) Name() string {
+	return q.name
+}
+
+// doLabeled call fn with pprof labels of the queue and worker applied to current goroutine,
+// so CPU and goroutine profiles can be sliced per queue and job. labels of submitter context are kept.
+//
+//	workerq:     name of the queue, if set
+//	worker_id:   id of the worker
+//	worker_name: name of the worker, if set
+func (q *WorkerQueue) doLabeled(worker *Worker, fn func()) {
+	labels := make([]string, 0, 6)
+	if q.name != "" {
+		labels = append(labels, "workerq", q.name)
+	}
+	labels = append(labels, "worker_id", strconv.FormatUint(worker.id, 10))
+	if worker.name != "" {
+		labels = append(labels, "worker_name", worker.name)
+	}
+	pprof.Do(worker.base, pprof.Labels(labels...), func(context.Context) {
+		fn()
+	})
+}
@@ -0,0 +1,44 @@
+package workerq
+
+import (
+	"bytes"
+	"context"
+	"runtime/pprof"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestWorkerQueue_SetName(t *testing.T) {
+	q := New(1).SetName("mail").Start()
+	defer q.Stop()
+	if q.Name() != "mail" {
+		t.Errorf("except mail, actual %s", q.Name())
+	}
+
+	begin, release := make(chan struct{}), make(chan struct{})
+	ctx := pprof.WithLabels(context.Background(), pprof.Labels("tenant", "t1"))
+	worker := NewWorker(ctx, func(worker *Worker) error {
+		close(begin)
+		<-release
+		return nil
+	}).SetName("send")
+	q.AddWorker(worker)
+	<-begin
+
+	buf := &bytes.Buffer{}
+	pprof.Lookup("goroutine").WriteTo(buf, 1)
+	close(release)
+	worker.Wait()
+
+	for _, label := range []string{
+		`"workerq":"mail"`,
+		`"worker_id":"` + strconv.FormatUint(worker.ID(), 10) + `"`,
+		`"worker_name":"send"`,
+		`"tenant":"t1"`,
+	} {
+		if !strings.Contains(buf.String(), label) {
+			t.Errorf("except label %s in goroutine profile", label)
+		}
+	}
+}
@@ -0,0 +1,56 @@
+package workerq
+
+import (
+	"context"
+	"log/slog"
+	"time"
+)
+
+// SetLogger log lifecycle events of workers and the queue to l, nil disable logging.
+//
+//	debug: worker started and finished
+//	info:  retry, concurrency changed, pause and resume
+//	warn:  worker failed, stuck worker detected
+//	error: worker panicked, with stack
+func (q *WorkerQueue) SetLogger(l *slog.Logger) *WorkerQueue {
+	q.logger.Store(l)
+	return q
+}
+
+func (q *WorkerQueue) log(level slog.Level, msg string, args ...interface{}) {
+	l := q.logger.Load()
+	if l == nil || !l.Enabled(context.Background(), level) {
+		return
+	}
+	l.Log(context.Background(), level, msg, args...)
+}
+
+func (q *WorkerQueue) logEnabled(level slog.Level) bool {
+	l := q.logger.Load()
+	return l != nil && l.Enabled(context.Background(), level)
+}
+
+func (c *Worker) logAttrs() slog.Attr {
+	if c.name == "" {
+		return slog.Group("worker", slog.Uint64("id", c.id))
+	}
+	return slog.Group("worker", slog.Uint64("id", c.id), slog.String("name", c.name))
+}
+
+func (q *WorkerQueue) logWorkerStart(worker *Worker, wait time.Duration) {
+	if q.logEnabled(slog.LevelDebug) {
+		q.log(slog.LevelDebug, "worker started", worker.logAttrs(), slog.Duration("wait", wait))
+	}
+}
+
+func (q *WorkerQueue) logWorkerFinish(worker *Worker, run time.Duration, err error) {
+	switch {
+	case worker.panicValue != nil:
+		q.log(slog.LevelError, "worker panicked", worker.logAttrs(), slog.Duration("run", run),
+			slog.Any("panic", worker.panicValue), slog.String("stack", string(worker.panicStack)))
+	case err != nil:
+		q.log(slog.LevelWarn, "worker failed", worker.logAttrs(), slog.Duration("run", run), slog.Any("error", err))
+	case q.logEnabled(slog.LevelDebug):
+		q.log(slog.LevelDebug, "worker finished", worker.logAttrs(), slog.Duration("run", run))
+	}
+}
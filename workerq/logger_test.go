@@ -0,0 +1,71 @@
+package workerq
+
+import (
+	"bytes"
+	"errors"
+	"log/slog"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// syncBuffer is bytes.Buffer safe for concurrent logging.
+type syncBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	var (
+		n   int
+		err error
+	)
+	withLock(&b.mu, func() {
+		n, err = b.buf.Write(p)
+	})
+	return n, err
+}
+
+func (b *syncBuffer) String() string {
+	var s string
+	withLock(&b.mu, func() {
+		s = b.buf.String()
+	})
+	return s
+}
+
+func TestWorkerQueue_SetLogger(t *testing.T) {
+	buf := &syncBuffer{}
+	logger := slog.New(slog.NewTextHandler(buf, &slog.HandlerOptions{Level: slog.LevelDebug}))
+	wq := New(1).SetLogger(logger).Start()
+	defer wq.Stop()
+
+	wq.AddWorker(NewWorker(nil, func(worker *Worker) error {
+		return nil
+	}).SetName("ok"))
+	wq.AddWorkerFunc(nil, Retry(2, time.Millisecond, func(worker *Worker) error {
+		return errors.New("failed")
+	})).Wait()
+	wq.AddWorkerFunc(nil, func(worker *Worker) error {
+		panic("worker panic")
+	}).Wait()
+	wq.SetConcurrency(2)
+
+	text := buf.String()
+	for _, s := range []string{
+		`level=DEBUG msg="worker started" worker.id=`,
+		`level=DEBUG msg="worker finished" worker.id=`,
+		`worker.name=ok`,
+		`level=INFO msg="worker retry"`,
+		`attempt=2 error=failed`,
+		`level=WARN msg="worker failed"`,
+		`level=ERROR msg="worker panicked"`,
+		`panic="worker panic" stack=`,
+		`level=INFO msg="concurrency changed" from=1 to=2`,
+	} {
+		if !strings.Contains(text, s) {
+			t.Errorf("except %q in log:\n%s", s, text)
+		}
+	}
+}
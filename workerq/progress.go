@@ -0,0 +1,139 @@
+package workerq
+
+import (
+	"sync"
+	"time"
+)
+
+// progressBuffer is buffer size of progress subscribe channel.
+const progressBuffer = 16
+
+// Progress is reported by worker through Worker.ReportProgress.
+type Progress struct {
+	WorkerID uint64
+	Fraction float64 // range [0, 1]
+	Message  string
+	Time     time.Time
+}
+
+// ReportProgress report how far along the worker is, fraction will be limited to [0, 1].
+// the progress is streamed to subscribers of the worker and the queue it added to.
+func (c *Worker) ReportProgress(fraction float64, message string) {
+	if fraction < 0 {
+		fraction = 0
+	} else if fraction > 1 {
+		fraction = 1
+	}
+	p := Progress{
+		WorkerID: c.id,
+		Fraction: fraction,
+		Message:  message,
+		Time:     time.Now(),
+	}
+	c.progress.publish(p)
+	if c.q != nil {
+		c.q.progress.publish(p)
+	}
+}
+
+// Progress return the latest progress reported by worker.
+func (c *Worker) Progress() Progress {
+	p, ok := c.progress.latest()
+	if !ok {
+		p.WorkerID = c.id
+	}
+	return p
+}
+
+// SubscribeProgress subscribe progress of the worker, the channel will be closed when worker done
+// or unsubscribe func called. slow subscriber only loses the oldest progress.
+func (c *Worker) SubscribeProgress() (<-chan Progress, func()) {
+	return c.progress.subscribe()
+}
+
+// SubscribeProgress subscribe progress of all workers added to the queue,
+// the channel will be closed when unsubscribe func called.
+func (q *WorkerQueue) SubscribeProgress() (<-chan Progress, func()) {
+	return q.progress.subscribe()
+}
+
+// progressHub broadcast progress to subscribers without blocking the publisher.
+type progressHub struct {
+	mu      sync.Mutex
+	subs    map[chan Progress]struct{}
+	last    Progress
+	hasLast bool
+	closed  bool
+}
+
+func (h *progressHub) subscribe() (<-chan Progress, func()) {
+	ch := make(chan Progress, progressBuffer)
+	withLock(&h.mu, func() {
+		if h.closed {
+			close(ch)
+			return
+		}
+		if h.subs == nil {
+			h.subs = make(map[chan Progress]struct{})
+		}
+		h.subs[ch] = struct{}{}
+	})
+	unsubscribe := func() {
+		withLock(&h.mu, func() {
+			if _, ok := h.subs[ch]; ok {
+				delete(h.subs, ch)
+				close(ch)
+			}
+		})
+	}
+	return ch, unsubscribe
+}
+
+func (h *progressHub) publish(p Progress) {
+	withLock(&h.mu, func() {
+		if h.closed {
+			return
+		}
+		h.last, h.hasLast = p, true
+		for ch := range h.subs {
+			select {
+			case ch <- p:
+				continue
+			default:
+			}
+			// subscriber is full, drop the oldest one.
+			select {
+			case <-ch:
+			default:
+			}
+			select {
+			case ch <- p:
+			default:
+			}
+		}
+	})
+}
+
+func (h *progressHub) latest() (Progress, bool) {
+	var (
+		p  Progress
+		ok bool
+	)
+	withLock(&h.mu, func() {
+		p, ok = h.last, h.hasLast
+	})
+	return p, ok
+}
+
+func (h *progressHub) close() {
+	withLock(&h.mu, func() {
+		if h.closed {
+			return
+		}
+		h.closed = true
+		for ch := range h.subs {
+			close(ch)
+		}
+		h.subs = nil
+	})
+}
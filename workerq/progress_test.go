@@ -0,0 +1,78 @@
+package workerq
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestWorker_ReportProgress(t *testing.T) {
+	wq := New(1).Start()
+	defer wq.Stop()
+
+	qch, unsubscribe := wq.SubscribeProgress()
+	defer unsubscribe()
+
+	release := make(chan struct{})
+	worker := wq.AddWorkerFunc(nil, func(worker *Worker) error {
+		<-release
+		worker.ReportProgress(0.5, "half")
+		worker.ReportProgress(2, "done")
+		return nil
+	})
+	wch, _ := worker.SubscribeProgress()
+	close(release)
+
+	fractions := make([]float64, 0, 2)
+	for p := range wch {
+		if p.WorkerID != worker.ID() {
+			t.Errorf("except worker id %v, actual %v", worker.ID(), p.WorkerID)
+		}
+		fractions = append(fractions, p.Fraction)
+	}
+	if len(fractions) != 2 || fractions[0] != 0.5 || fractions[1] != 1 {
+		t.Errorf("except [0.5 1], actual %v", fractions)
+	}
+
+	if p := <-qch; p.Message != "half" {
+		t.Errorf("except half, actual %v", p.Message)
+	}
+	if p := <-qch; p.Message != "done" {
+		t.Errorf("except done, actual %v", p.Message)
+	}
+	if p := worker.Progress(); p.Fraction != 1 {
+		t.Errorf("except latest fraction 1, actual %v", p.Fraction)
+	}
+
+	// subscribe after worker done, channel is closed immediately.
+	ch, _ := worker.SubscribeProgress()
+	if _, ok := <-ch; ok {
+		t.Error("except closed progress channel")
+	}
+}
+
+func TestWorker_SubscribeProgressCanceled(t *testing.T) {
+	wq := New(1).Start()
+	defer wq.Stop()
+
+	release := make(chan struct{})
+	defer close(release)
+	wq.AddWorkerFunc(nil, func(worker *Worker) error {
+		<-release
+		return nil
+	})
+	ctx, cancel := context.WithCancel(context.Background())
+	queued := wq.AddWorkerFunc(ctx, nil)
+	ch, _ := queued.SubscribeProgress()
+
+	// ctx of caller canceled while queued, the channel is closed.
+	cancel()
+	select {
+	case _, ok := <-ch:
+		if ok {
+			t.Error("except closed progress channel")
+		}
+	case <-time.After(time.Second):
+		t.Fatal("except progress channel closed once worker done")
+	}
+}
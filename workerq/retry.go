@@ -0,0 +1,57 @@
+package workerq
+
+import (
+	"log/slog"
+	"time"
+
+	"go.uber.org/multierr"
+
+	"github.com/luweimy/goutil/observer"
+)
+
+// Retry wrap wf to retry at most attempts times with backoff between attempts while it returns error,
+// retrying stops once the worker done. the returned error combines errors of all attempts.
+func Retry(attempts int, backoff time.Duration, wf WorkerFunc) WorkerFunc {
+	return func(worker *Worker) error {
+		var errs error
+		for attempt := 1; ; attempt++ {
+			start := time.Now()
+			err := wf(worker)
+			if err == nil {
+				return nil
+			}
+			errs = multierr.Append(errs, err)
+			if attempt >= attempts {
+				return errs
+			}
+			worker.retry(attempt+1, start, err)
+
+			timer := time.NewTimer(backoff)
+			select {
+			case <-timer.C:
+			case <-worker.Done():
+				timer.Stop()
+				return errs
+			}
+		}
+	}
+}
+
+// retry notify observer of queue the worker will retry.
+func (c *Worker) retry(attempt int, start time.Time, err error) {
+	if c.q == nil {
+		return
+	}
+	c.q.log(slog.LevelInfo, "worker retry", c.logAttrs(), slog.Int("attempt", attempt), slog.Any("error", err))
+	if c.q.obs == nil {
+		return
+	}
+	now := time.Now()
+	c.q.obs.OnWorkerRetry(observer.WorkerEvent{
+		Time:     now,
+		WorkerID: c.id,
+		Run:      now.Sub(start),
+		Err:      err,
+		Attempt:  attempt,
+	})
+}
@@ -0,0 +1,104 @@
+package workerq
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"go.uber.org/multierr"
+
+	"github.com/luweimy/goutil/observer"
+)
+
+type recordObserver struct {
+	observer.Nop
+	mu     sync.Mutex
+	events []observer.WorkerEvent
+	kinds  []string
+}
+
+func (o *recordObserver) record(kind string, e observer.WorkerEvent) {
+	withLock(&o.mu, func() {
+		o.kinds = append(o.kinds, kind)
+		o.events = append(o.events, e)
+	})
+}
+
+func (o *recordObserver) OnWorkerStart(e observer.WorkerEvent)  { o.record("start", e) }
+func (o *recordObserver) OnWorkerFinish(e observer.WorkerEvent) { o.record("finish", e) }
+func (o *recordObserver) OnWorkerPanic(e observer.WorkerEvent)  { o.record("panic", e) }
+func (o *recordObserver) OnWorkerRetry(e observer.WorkerEvent)  { o.record("retry", e) }
+
+func (o *recordObserver) snapshot() ([]string, []observer.WorkerEvent) {
+	var (
+		kinds  []string
+		events []observer.WorkerEvent
+	)
+	withLock(&o.mu, func() {
+		kinds = append(kinds, o.kinds...)
+		events = append(events, o.events...)
+	})
+	return kinds, events
+}
+
+func TestRetry(t *testing.T) {
+	obs := &recordObserver{}
+	wq := NewWithObserver(1, obs).Start()
+	defer wq.Stop()
+
+	errFailed := errors.New("failed")
+	calls := 0
+	worker := wq.AddWorkerFunc(nil, Retry(3, time.Millisecond*10, func(worker *Worker) error {
+		calls++
+		if calls < 3 {
+			return errFailed
+		}
+		return nil
+	}))
+	if err := worker.Wait(); err != nil {
+		t.Error(err)
+	}
+
+	worker = wq.AddWorkerFunc(nil, Retry(2, time.Millisecond*10, func(worker *Worker) error {
+		return errFailed
+	}))
+	if errs := multierr.Errors(worker.Wait()); len(errs) != 2 {
+		t.Errorf("except 2 errors, actual %v", errs)
+	}
+
+	kinds, events := obs.snapshot()
+	except := []string{"start", "retry", "retry", "finish", "start", "retry", "finish"}
+	if len(kinds) != len(except) {
+		t.Fatalf("except %v, actual %v", except, kinds)
+	}
+	for i := range except {
+		if kinds[i] != except[i] {
+			t.Errorf("except %v, actual %v", except, kinds)
+			break
+		}
+	}
+	if events[2].Attempt != 3 || events[2].Err != errFailed {
+		t.Errorf("except the third attempt, actual %+v", events[2])
+	}
+}
+
+func TestNewWithObserver_Panic(t *testing.T) {
+	obs := &recordObserver{}
+	wq := NewWithObserver(1, obs).Start()
+	defer wq.Stop()
+
+	worker := wq.AddWorkerFunc(nil, func(worker *Worker) error {
+		time.Sleep(time.Millisecond * 10)
+		panic("worker panic")
+	})
+	worker.Wait()
+
+	kinds, events := obs.snapshot()
+	if len(kinds) != 3 || kinds[1] != "panic" || events[1].Panic != "worker panic" || len(events[1].Stack) == 0 {
+		t.Errorf("except panic event, actual %v %+v", kinds, events)
+	}
+	if events[2].Run < time.Millisecond*10 {
+		t.Errorf("except run duration >= 10ms, actual %v", events[2].Run)
+	}
+}
@@ -0,0 +1,73 @@
+package workerq
+
+import (
+	"math"
+	"sort"
+)
+
+// sketchAccuracy is relative accuracy of quantiles estimated by sketch.
+const sketchAccuracy = 0.01
+
+// sketch is streaming quantile sketch of positive values in logarithmic buckets,
+// quantiles are estimated within sketchAccuracy relative error in constant memory of value range.
+type sketch struct {
+	gamma   float64
+	buckets map[int]uint64 // count of values in (gamma^(i-1), gamma^i]
+	zeros   uint64         // count of values <= 0
+	count   uint64
+}
+
+func newSketch() *sketch {
+	return &sketch{
+		gamma:   (1 + sketchAccuracy) / (1 - sketchAccuracy),
+		buckets: make(map[int]uint64),
+	}
+}
+
+func (s *sketch) add(v float64) {
+	s.count++
+	if v <= 0 {
+		s.zeros++
+		return
+	}
+	s.buckets[int(math.Ceil(math.Log(v)/math.Log(s.gamma)))]++
+}
+
+// quantile return estimated value at quantile q of values of sketches, 0 if they are empty.
+func quantile(q float64, sketches ...*sketch) float64 {
+	var (
+		count   uint64
+		zeros   uint64
+		buckets = make(map[int]uint64)
+		gamma   float64
+	)
+	for _, s := range sketches {
+		count += s.count
+		zeros += s.zeros
+		gamma = s.gamma
+		for i, n := range s.buckets {
+			buckets[i] += n
+		}
+	}
+	if count == 0 {
+		return 0
+	}
+	rank := uint64(q * float64(count-1))
+	if rank < zeros {
+		return 0
+	}
+	keys := make([]int, 0, len(buckets))
+	for i := range buckets {
+		keys = append(keys, i)
+	}
+	sort.Ints(keys)
+	seen := zeros
+	for _, i := range keys {
+		seen += buckets[i]
+		if seen > rank {
+			// middle of bucket keeps relative error within accuracy.
+			return 2 * math.Pow(gamma, float64(i)) / (gamma + 1)
+		}
+	}
+	return 2 * math.Pow(gamma, float64(keys[len(keys)-1])) / (gamma + 1)
+}
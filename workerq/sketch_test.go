@@ -0,0 +1,27 @@
+package workerq
+
+import (
+	"math"
+	"testing"
+)
+
+func TestSketch(t *testing.T) {
+	a, b := newSketch(), newSketch()
+	for i := 1; i <= 1000; i++ {
+		a.add(float64(i))
+	}
+	b.add(0)
+	for _, c := range []struct {
+		q, except float64
+	}{{0.5, 500}, {0.95, 950}, {0.99, 990}} {
+		if v := quantile(c.q, a, b); math.Abs(v-c.except)/c.except > sketchAccuracy*2 {
+			t.Errorf("except %v at %v, actual %v", c.except, c.q, v)
+		}
+	}
+	if v := quantile(0, a, b); v != 0 {
+		t.Errorf("except 0, actual %v", v)
+	}
+	if v := quantile(0.5, newSketch()); v != 0 {
+		t.Errorf("except 0 of empty sketch, actual %v", v)
+	}
+}
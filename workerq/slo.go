@@ -0,0 +1,185 @@
+package workerq
+
+import (
+	"context"
+	"log/slog"
+	"time"
+)
+
+// SLO is objective of time workers wait in backlog, zero threshold is not checked.
+type SLO struct {
+	P95        time.Duration // threshold of p95 wait time
+	P99        time.Duration // threshold of p99 wait time
+	BacklogAge time.Duration // threshold of age of the oldest backlog worker
+
+	Window     time.Duration // wait time quantiles are computed over recent window, default 1 minute
+	Interval   time.Duration // interval of checking thresholds, default 1 second
+	Hysteresis float64       // firing alert resolves once value below threshold*(1-Hysteresis), default 0.1
+}
+
+// SLOMetric is metric checked by SLO.
+type SLOMetric string
+
+const (
+	SLOWaitP95    SLOMetric = "wait_p95"
+	SLOWaitP99    SLOMetric = "wait_p99"
+	SLOBacklogAge SLOMetric = "backlog_age"
+)
+
+// SLOAlert is notified when metric crossed threshold, and again when it resolved.
+type SLOAlert struct {
+	Metric    SLOMetric
+	Value     time.Duration
+	Threshold time.Duration
+	Firing    bool // true if crossed, false if resolved
+	Time      time.Time
+}
+
+type sloState struct {
+	slo  SLO
+	hook func(SLOAlert)
+	stop context.CancelFunc
+
+	// guarded by runningMu of queue
+	cur, prev *sketch
+	rotatedAt time.Time
+	firing    map[SLOMetric]bool
+}
+
+// SetSLO track wait time of workers in a streaming quantile sketch, check slo every interval,
+// hook is called when a threshold crossed and when the alert resolved.
+// an alert fires once value exceeds threshold, and resolves once it drops below threshold*(1-Hysteresis),
+// so value hovering around threshold does not flap.
+// checking backlog age scans backlog workers. slo without thresholds disable tracking.
+func (q *WorkerQueue) SetSLO(slo SLO, hook func(SLOAlert)) {
+	if slo.Window <= 0 {
+		slo.Window = time.Minute
+	}
+	if slo.Interval <= 0 {
+		slo.Interval = time.Second
+	}
+	if slo.Hysteresis <= 0 || slo.Hysteresis >= 1 {
+		slo.Hysteresis = 0.1
+	}
+	var s *sloState
+	if slo.P95 > 0 || slo.P99 > 0 || slo.BacklogAge > 0 {
+		ctx, cancel := context.WithCancel(q.ctx)
+		s = &sloState{
+			slo:       slo,
+			hook:      hook,
+			stop:      cancel,
+			cur:       newSketch(),
+			prev:      newSketch(),
+			rotatedAt: time.Now(),
+			firing:    make(map[SLOMetric]bool),
+		}
+		go q.runSLO(ctx, s)
+	}
+	withLock(&q.runningMu, func() {
+		if q.slo != nil {
+			q.slo.stop()
+		}
+		q.slo = s
+	})
+}
+
+// WaitQuantile return estimated wait time at quantile p(e.g. 0.99) over the SLO window,
+// 0 if SLO is not set or no worker started in window.
+func (q *WorkerQueue) WaitQuantile(p float64) time.Duration {
+	var d time.Duration
+	withLock(&q.runningMu, func() {
+		if s := q.slo; s != nil {
+			s.rotate(time.Now())
+			d = time.Duration(quantile(p, s.prev, s.cur))
+		}
+	})
+	return d
+}
+
+// observeWait must be called with runningMu held.
+func (q *WorkerQueue) observeWait(wait time.Duration, now time.Time) {
+	if s := q.slo; s != nil {
+		s.rotate(now)
+		s.cur.add(float64(wait))
+	}
+}
+
+// rotate sketches every half window, so quantiles cover between half and full window.
+func (s *sloState) rotate(now time.Time) {
+	half := s.slo.Window / 2
+	if now.Sub(s.rotatedAt) < half {
+		return
+	}
+	if now.Sub(s.rotatedAt) >= s.slo.Window {
+		s.prev = newSketch() // no worker started in last half window
+	} else {
+		s.prev = s.cur
+	}
+	s.cur = newSketch()
+	s.rotatedAt = now
+}
+
+func (q *WorkerQueue) runSLO(ctx context.Context, s *sloState) {
+	ticker := time.NewTicker(s.slo.Interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			for _, alert := range q.checkSLO(s) {
+				if alert.Firing {
+					q.log(slog.LevelWarn, "slo alert firing", slog.String("metric", string(alert.Metric)),
+						slog.Duration("value", alert.Value), slog.Duration("threshold", alert.Threshold))
+				} else {
+					q.log(slog.LevelInfo, "slo alert resolved", slog.String("metric", string(alert.Metric)),
+						slog.Duration("value", alert.Value), slog.Duration("threshold", alert.Threshold))
+				}
+				if s.hook != nil {
+					s.hook(alert)
+				}
+			}
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// checkSLO return alerts of metrics changed state.
+func (q *WorkerQueue) checkSLO(s *sloState) []SLOAlert {
+	var alerts []SLOAlert
+	withLock(&q.runningMu, func() {
+		now := time.Now()
+		s.rotate(now)
+		check := func(metric SLOMetric, value, threshold time.Duration) {
+			if threshold <= 0 {
+				return
+			}
+			firing := s.firing[metric]
+			switch {
+			case !firing && value > threshold:
+				firing = true
+			case firing && float64(value) < float64(threshold)*(1-s.slo.Hysteresis):
+				firing = false
+			default:
+				return
+			}
+			s.firing[metric] = firing
+			alerts = append(alerts, SLOAlert{Metric: metric, Value: value, Threshold: threshold, Firing: firing, Time: now})
+		}
+		if s.slo.P95 > 0 {
+			check(SLOWaitP95, time.Duration(quantile(0.95, s.prev, s.cur)), s.slo.P95)
+		}
+		if s.slo.P99 > 0 {
+			check(SLOWaitP99, time.Duration(quantile(0.99, s.prev, s.cur)), s.slo.P99)
+		}
+		if s.slo.BacklogAge > 0 {
+			var age time.Duration
+			for _, worker := range q.queued {
+				if d := now.Sub(worker.enqueuedAt); d > age {
+					age = d
+				}
+			}
+			check(SLOBacklogAge, age, s.slo.BacklogAge)
+		}
+	})
+	return alerts
+}
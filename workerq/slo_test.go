@@ -0,0 +1,90 @@
+package workerq
+
+import (
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestWorkerQueue_SetSLO(t *testing.T) {
+	q := New(1).Start()
+	defer q.Stop()
+
+	var (
+		mu     sync.Mutex
+		alerts []SLOAlert
+	)
+	q.SetSLO(SLO{P99: time.Millisecond * 30, BacklogAge: time.Millisecond * 50, Interval: time.Millisecond * 10},
+		func(alert SLOAlert) {
+			withLock(&mu, func() {
+				alerts = append(alerts, alert)
+			})
+		})
+	firing := func(metric SLOMetric) (firing bool, n int) {
+		withLock(&mu, func() {
+			for _, alert := range alerts {
+				if alert.Metric == metric {
+					firing = alert.Firing
+					n++
+				}
+			}
+		})
+		return
+	}
+
+	// the first worker blocks others in backlog.
+	block := q.AddWorkerFunc(nil, func(worker *Worker) error {
+		time.Sleep(time.Millisecond * 100)
+		return nil
+	})
+	var workers []*Worker
+	for i := 0; i < 3; i++ {
+		workers = append(workers, q.AddWorkerFunc(nil, nil))
+	}
+	time.Sleep(time.Millisecond * 80)
+	if f, _ := firing(SLOBacklogAge); !f {
+		t.Error("except backlog age alert firing")
+	}
+	block.Wait()
+	for _, worker := range workers {
+		worker.Wait()
+	}
+	time.Sleep(time.Millisecond * 50)
+
+	if f, n := firing(SLOBacklogAge); f || n != 2 {
+		t.Errorf("except backlog age alert fired and resolved once, actual firing %v alerts %d", f, n)
+	}
+	if f, n := firing(SLOWaitP99); !f || n != 1 {
+		t.Errorf("except p99 alert firing, actual firing %v alerts %d", f, n)
+	}
+	if d := q.WaitQuantile(0.99); d < time.Millisecond*80 {
+		t.Errorf("except p99 wait >= 80ms, actual %v", d)
+	}
+	if _, n := firing(SLOWaitP95); n != 0 {
+		t.Errorf("except p95 not checked, actual alerts %d", n)
+	}
+}
+
+func TestSLOState_Hysteresis(t *testing.T) {
+	q := New(1)
+	q.SetSLO(SLO{BacklogAge: time.Second, Interval: time.Hour}, nil)
+	defer q.Stop()
+
+	s := q.slo
+	worker := NewWorker(nil, nil)
+	q.queued[worker.id] = worker
+	for _, c := range []struct {
+		age    time.Duration
+		alerts int
+	}{
+		{time.Millisecond * 1100, 1}, // fire
+		{time.Millisecond * 950, 0},  // below threshold but within hysteresis
+		{time.Millisecond * 1050, 0}, // still firing
+		{time.Millisecond * 850, 1},  // resolve
+	} {
+		worker.enqueuedAt = time.Now().Add(-c.age)
+		if alerts := q.checkSLO(s); len(alerts) != c.alerts {
+			t.Errorf("age %v, except %d alerts, actual %v", c.age, c.alerts, alerts)
+		}
+	}
+}
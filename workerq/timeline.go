@@ -0,0 +1,206 @@
+package workerq
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"time"
+)
+
+// TimelineEntry is a finished worker recorded by timeline of queue.
+type TimelineEntry struct {
+	WorkerID   uint64
+	Name       string
+	Slot       int // executor slot processed the worker, in [0, concurrency)
+	EnqueuedAt time.Time
+	StartedAt  time.Time
+	FinishedAt time.Time
+	Failed     bool
+}
+
+// stall is a SetConcurrency call blocked dispatching until processing workers done.
+type stall struct {
+	start, end time.Time
+	from, to   int
+}
+
+type timeline struct {
+	max     int
+	entries []TimelineEntry // ring of recent entries
+	at      int
+	stalls  []stall // ring of recent stalls
+	stallAt int
+	busy    []bool // busy executor slots
+}
+
+// SetTimeline record enqueue, start and end time and executor slot of at most max recent finished workers,
+// and the stalls of SetConcurrency. max <= 0 disable the timeline and drop recorded entries.
+func (q *WorkerQueue) SetTimeline(max int) *WorkerQueue {
+	withLock(&q.runningMu, func() {
+		if max <= 0 {
+			q.timeline = nil
+			return
+		}
+		q.timeline = &timeline{max: max}
+	})
+	return q
+}
+
+// Timeline return recorded workers, the oldest finished first.
+func (q *WorkerQueue) Timeline() []TimelineEntry {
+	var entries []TimelineEntry
+	withLock(&q.runningMu, func() {
+		if tl := q.timeline; tl != nil {
+			entries = make([]TimelineEntry, 0, len(tl.entries))
+			entries = append(entries, tl.entries[tl.at:]...)
+			entries = append(entries, tl.entries[:tl.at]...)
+		}
+	})
+	return entries
+}
+
+// acquireSlot must be called with runningMu held.
+func (q *WorkerQueue) acquireSlot(worker *Worker) {
+	tl := q.timeline
+	if tl == nil {
+		return
+	}
+	// lowest free slot first, so slots in use stay below concurrency after it decreased.
+	for i, busy := range tl.busy {
+		if !busy {
+			tl.busy[i] = true
+			worker.slot = i + 1
+			return
+		}
+	}
+	tl.busy = append(tl.busy, true)
+	worker.slot = len(tl.busy)
+}
+
+func (q *WorkerQueue) recordTimeline(worker *Worker, start, end time.Time, err error) {
+	withLock(&q.runningMu, func() {
+		tl := q.timeline
+		if tl == nil || worker.slot == 0 {
+			return
+		}
+		slot := worker.slot - 1
+		if slot < len(tl.busy) {
+			tl.busy[slot] = false
+		}
+		e := TimelineEntry{
+			WorkerID:   worker.id,
+			Name:       worker.name,
+			Slot:       slot,
+			EnqueuedAt: worker.enqueuedAt,
+			StartedAt:  start,
+			FinishedAt: end,
+			Failed:     err != nil,
+		}
+		if len(tl.entries) < tl.max {
+			tl.entries = append(tl.entries, e)
+			return
+		}
+		tl.entries[tl.at] = e
+		tl.at = (tl.at + 1) % tl.max
+	})
+}
+
+func (q *WorkerQueue) recordStall(start time.Time, from, to int) {
+	withLock(&q.runningMu, func() {
+		tl := q.timeline
+		if tl == nil {
+			return
+		}
+		s := stall{start: start, end: time.Now(), from: from, to: to}
+		if len(tl.stalls) < tl.max {
+			tl.stalls = append(tl.stalls, s)
+			return
+		}
+		tl.stalls[tl.stallAt] = s
+		tl.stallAt = (tl.stallAt + 1) % tl.max
+	})
+}
+
+// traceEvent is event of Chrome Trace Event Format.
+type traceEvent struct {
+	Name string                 `json:"name"`
+	Cat  string                 `json:"cat,omitempty"`
+	Ph   string                 `json:"ph"`
+	Ts   float64                `json:"ts"` // microseconds
+	Dur  float64                `json:"dur,omitempty"`
+	Pid  int                    `json:"pid"`
+	Tid  int                    `json:"tid"`
+	ID   uint64                 `json:"id,omitempty"`
+	Args map[string]interface{} `json:"args,omitempty"`
+}
+
+// WriteChromeTrace write the timeline as Chrome Trace Event JSON, load it in chrome://tracing or Perfetto.
+// thread 0 "queue" shows time workers waited in backlog and SetConcurrency stalls,
+// thread n shows workers processed by executor slot n-1, gaps between them are idle concurrency.
+func (q *WorkerQueue) WriteChromeTrace(w io.Writer) error {
+	var (
+		entries []TimelineEntry
+		stalls  []stall
+	)
+	withLock(&q.runningMu, func() {
+		if tl := q.timeline; tl != nil {
+			entries = append(entries, tl.entries...)
+			stalls = append(stalls, tl.stalls...)
+		}
+	})
+
+	var base time.Time
+	for _, e := range entries {
+		if base.IsZero() || e.EnqueuedAt.Before(base) {
+			base = e.EnqueuedAt
+		}
+	}
+	for _, s := range stalls {
+		if base.IsZero() || s.start.Before(base) {
+			base = s.start
+		}
+	}
+	ts := func(t time.Time) float64 {
+		return float64(t.Sub(base).Nanoseconds()) / 1e3
+	}
+
+	events := []traceEvent{
+		{Name: "process_name", Ph: "M", Pid: 1, Args: map[string]interface{}{"name": "workerq"}},
+		{Name: "thread_name", Ph: "M", Pid: 1, Tid: 0, Args: map[string]interface{}{"name": "queue"}},
+	}
+	slots := 0
+	for _, e := range entries {
+		if e.Slot+1 > slots {
+			slots = e.Slot + 1
+		}
+	}
+	for i := 0; i < slots; i++ {
+		events = append(events, traceEvent{Name: "thread_name", Ph: "M", Pid: 1, Tid: i + 1,
+			Args: map[string]interface{}{"name": fmt.Sprintf("slot %d", i)}})
+	}
+	for _, e := range entries {
+		name := e.Name
+		if name == "" {
+			name = fmt.Sprintf("worker %d", e.WorkerID)
+		}
+		events = append(events,
+			traceEvent{Name: name, Cat: "queued", Ph: "b", Ts: ts(e.EnqueuedAt), Pid: 1, ID: e.WorkerID},
+			traceEvent{Name: name, Cat: "queued", Ph: "e", Ts: ts(e.StartedAt), Pid: 1, ID: e.WorkerID},
+			traceEvent{Name: name, Cat: "running", Ph: "X", Ts: ts(e.StartedAt), Dur: ts(e.FinishedAt) - ts(e.StartedAt),
+				Pid: 1, Tid: e.Slot + 1, Args: map[string]interface{}{
+					"worker_id": e.WorkerID,
+					"wait_ms":   float64(e.StartedAt.Sub(e.EnqueuedAt).Nanoseconds()) / 1e6,
+					"failed":    e.Failed,
+				}},
+		)
+	}
+	for _, s := range stalls {
+		events = append(events, traceEvent{Name: "SetConcurrency", Cat: "stall", Ph: "X", Ts: ts(s.start),
+			Dur: ts(s.end) - ts(s.start), Pid: 1, Tid: 0, Args: map[string]interface{}{"from": s.from, "to": s.to}})
+	}
+
+	return json.NewEncoder(w).Encode(struct {
+		TraceEvents     []traceEvent `json:"traceEvents"`
+		DisplayTimeUnit string       `json:"displayTimeUnit"`
+	}{events, "ms"})
+}
@@ -0,0 +1,69 @@
+package workerq
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestWorkerQueue_SetTimeline(t *testing.T) {
+	q := New(2).SetTimeline(3).Start()
+	defer q.Stop()
+
+	var workers []*Worker
+	for i := 0; i < 4; i++ {
+		i := i
+		workers = append(workers, q.AddWorkerFunc(nil, func(worker *Worker) error {
+			time.Sleep(time.Millisecond * 20)
+			if i == 3 {
+				return errors.New("failed")
+			}
+			return nil
+		}))
+	}
+	for _, worker := range workers {
+		worker.Wait()
+	}
+	q.SetConcurrency(1)
+
+	entries := q.Timeline()
+	if len(entries) != 3 {
+		t.Fatalf("except 3, actual %d", len(entries))
+	}
+	for _, e := range entries {
+		if e.Slot < 0 || e.Slot >= 2 {
+			t.Errorf("except slot in [0, 2), actual %d", e.Slot)
+		}
+		if e.StartedAt.Before(e.EnqueuedAt) || e.FinishedAt.Before(e.StartedAt) {
+			t.Errorf("entry times not except, %+v", e)
+		}
+		if e.Failed != (e.WorkerID == workers[3].ID()) {
+			t.Errorf("failed not except, %+v", e)
+		}
+	}
+
+	buf := &bytes.Buffer{}
+	if err := q.WriteChromeTrace(buf); err != nil {
+		t.Fatal(err)
+	}
+	var trace struct {
+		TraceEvents []traceEvent `json:"traceEvents"`
+	}
+	if err := json.Unmarshal(buf.Bytes(), &trace); err != nil {
+		t.Fatal(err)
+	}
+	phases := map[string]int{}
+	for _, e := range trace.TraceEvents {
+		phases[e.Ph+"/"+e.Cat]++
+	}
+	if phases["X/running"] != 3 || phases["b/queued"] != 3 || phases["e/queued"] != 3 || phases["X/stall"] != 1 {
+		t.Errorf("trace events not except, %v", phases)
+	}
+
+	q.SetTimeline(0)
+	if entries := q.Timeline(); entries != nil {
+		t.Errorf("except nil, actual %v", entries)
+	}
+}
@@ -0,0 +1,69 @@
+package workerq
+
+import (
+	"context"
+
+	"github.com/luweimy/goutil/qtrace"
+)
+
+// SetTracer trace workers by t, it should be called before workers added, nil disable tracing.
+// each worker records two spans which are children of the span carried by context passed to NewWorker:
+//
+//	queued:  from added to queue to processing started
+//	running: from processing started to done, Worker.Context carries it for child spans of the work
+func (q *WorkerQueue) SetTracer(t qtrace.Tracer) *WorkerQueue {
+	q.tracer = t
+	return q
+}
+
+func (c *Worker) traceAttrs() []qtrace.Attr {
+	attrs := []qtrace.Attr{qtrace.Int64("worker.id", int64(c.id))}
+	if c.name != "" {
+		attrs = append(attrs, qtrace.String("worker.name", c.name))
+	}
+	return attrs
+}
+
+func (q *WorkerQueue) traceQueued(worker *Worker) {
+	if q.tracer == nil {
+		return
+	}
+	_, worker.queuedSpan = q.tracer.Start(worker.base, "queued", worker.traceAttrs()...)
+}
+
+func (q *WorkerQueue) traceStart(worker *Worker) {
+	if worker.queuedSpan != nil {
+		worker.queuedSpan.End()
+	}
+	if q.tracer == nil {
+		return
+	}
+	// worker.ctx derives from base, so running span is child of submitter as well.
+	ctx, span := q.tracer.Start(worker.ctx, "running", worker.traceAttrs()...)
+	withLock(&worker.mu, func() {
+		worker.traceCtx, worker.runSpan = ctx, span
+	})
+}
+
+func (q *WorkerQueue) traceFinish(worker *Worker, err error) {
+	var span qtrace.Span
+	withLock(&worker.mu, func() {
+		span = worker.runSpan
+	})
+	if span == nil {
+		return
+	}
+	if err != nil {
+		span.RecordError(err)
+	}
+	span.End()
+}
+
+// traceContext return context carries running span, nil if not traced.
+func (c *Worker) traceContext() context.Context {
+	var ctx context.Context
+	withLock(&c.mu, func() {
+		ctx = c.traceCtx
+	})
+	return ctx
+}
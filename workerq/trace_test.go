@@ -0,0 +1,46 @@
+package workerq
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/luweimy/goutil/qtrace"
+)
+
+func TestWorkerQueue_SetTracer(t *testing.T) {
+	r := qtrace.NewRecorder()
+	q := New(1).SetTracer(r).Start()
+	defer q.Stop()
+
+	ctx, submit := r.Start(context.Background(), "submit")
+	worker := NewWorker(ctx, func(worker *Worker) error {
+		_, span := r.Start(worker.Context(), "work")
+		span.End()
+		return errors.New("failed")
+	}).SetName("job")
+	q.AddWorker(worker)
+	worker.Wait()
+	submit.End()
+
+	spans := map[string]qtrace.SpanData{}
+	for _, s := range r.Spans() {
+		spans[s.Name] = s
+	}
+	root := spans["submit"]
+	for _, name := range []string{"queued", "running"} {
+		s := spans[name]
+		if s.TraceID != root.TraceID || s.ParentID != root.SpanID || s.Attrs["worker.name"] != "job" {
+			t.Errorf("except %s span is child of submit, actual %+v", name, s)
+		}
+	}
+	if s := spans["work"]; s.ParentID != spans["running"].SpanID {
+		t.Errorf("except work span is child of running, actual %+v", s)
+	}
+	if s := spans["running"]; s.Err != "failed" {
+		t.Errorf("except failed, actual %q", s.Err)
+	}
+	if !spans["queued"].End.After(spans["queued"].Start) || spans["running"].Start.Before(spans["queued"].End) {
+		t.Errorf("except running after queued, actual %+v", spans)
+	}
+}
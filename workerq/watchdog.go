@@ -0,0 +1,189 @@
+package workerq
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"runtime"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// StuckWorker is a processing worker missed heartbeats beyond the watchdog threshold.
+type StuckWorker struct {
+	Worker        *Worker
+	LastHeartbeat time.Time
+	Stack         string // stack of goroutine processing the worker
+}
+
+// Heartbeat tell the watchdog of queue the worker is alive.
+// heartbeat is optional, the watchdog only watch workers which heartbeat at least once.
+func (c *Worker) Heartbeat() {
+	atomic.StoreInt64(&c.heartbeat, time.Now().UnixNano())
+}
+
+// LastHeartbeat return time of last heartbeat, zero time if never heartbeat.
+func (c *Worker) LastHeartbeat() time.Time {
+	ns := atomic.LoadInt64(&c.heartbeat)
+	if ns == 0 {
+		return time.Time{}
+	}
+	return time.Unix(0, ns)
+}
+
+type watchdog struct {
+	threshold   time.Duration
+	cancelStuck bool
+	hook        func(StuckWorker)
+	stop        context.CancelFunc
+
+	mu    sync.Mutex
+	stuck map[uint64]StuckWorker
+}
+
+// SetWatchdog watch processing workers, a worker missed heartbeats beyond threshold is flagged as stuck,
+// hook(if not nil) is called once it flagged, and the worker is canceled if cancelStuck is true.
+// canceling a stuck worker can only notify it done, it still holds the slot until it returns.
+// threshold <= 0 disable the watchdog.
+func (q *WorkerQueue) SetWatchdog(threshold time.Duration, cancelStuck bool, hook func(StuckWorker)) {
+	var w *watchdog
+	if threshold > 0 {
+		ctx, cancel := context.WithCancel(q.ctx)
+		w = &watchdog{
+			threshold:   threshold,
+			cancelStuck: cancelStuck,
+			hook:        hook,
+			stop:        cancel,
+			stuck:       make(map[uint64]StuckWorker),
+		}
+		go q.runWatchdog(ctx, w)
+	}
+	withLock(&q.runningMu, func() {
+		if q.watchdog != nil {
+			q.watchdog.stop()
+		}
+		q.watchdog = w
+	})
+}
+
+// StuckWorkers return workers flagged as stuck by the watchdog currently.
+func (q *WorkerQueue) StuckWorkers() []StuckWorker {
+	var w *watchdog
+	withLock(&q.runningMu, func() {
+		w = q.watchdog
+	})
+	if w == nil {
+		return nil
+	}
+	var stuck []StuckWorker
+	withLock(&w.mu, func() {
+		stuck = make([]StuckWorker, 0, len(w.stuck))
+		for _, s := range w.stuck {
+			stuck = append(stuck, s)
+		}
+	})
+	return stuck
+}
+
+func (q *WorkerQueue) runWatchdog(ctx context.Context, w *watchdog) {
+	interval := w.threshold / 4
+	if interval < time.Millisecond*10 {
+		interval = time.Millisecond * 10
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			q.checkHeartbeats(w)
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+func (q *WorkerQueue) checkHeartbeats(w *watchdog) {
+	now := time.Now()
+	running := q.runningWorkers()
+	stale := make(map[uint64]bool, len(running))
+	flagged := make([]*Worker, 0)
+
+	withLock(&w.mu, func() {
+		for _, worker := range running {
+			last := worker.LastHeartbeat()
+			if last.IsZero() || now.Sub(last) <= w.threshold {
+				continue
+			}
+			stale[worker.id] = true
+			if _, ok := w.stuck[worker.id]; !ok {
+				flagged = append(flagged, worker)
+			}
+		}
+		// worker heartbeat again or done is not stuck any more.
+		for id := range w.stuck {
+			if !stale[id] {
+				delete(w.stuck, id)
+			}
+		}
+	})
+	if len(flagged) == 0 {
+		return
+	}
+
+	stacks := goroutineStacks()
+	for _, worker := range flagged {
+		s := StuckWorker{
+			Worker:        worker,
+			LastHeartbeat: worker.LastHeartbeat(),
+			Stack:         stacks[atomic.LoadInt64(&worker.goid)],
+		}
+		withLock(&w.mu, func() {
+			w.stuck[worker.id] = s
+		})
+		q.log(slog.LevelWarn, "worker stuck", worker.logAttrs(), slog.Time("last_heartbeat", s.LastHeartbeat),
+			slog.Bool("cancel", w.cancelStuck), slog.String("stack", s.Stack))
+		if w.hook != nil {
+			w.hook(s)
+		}
+		if w.cancelStuck {
+			worker.cancel()
+		}
+	}
+}
+
+// goroutineStacks dump stacks of all goroutines index by goroutine id.
+func goroutineStacks() map[int64]string {
+	buf := make([]byte, 64<<10)
+	for {
+		n := runtime.Stack(buf, true)
+		if n < len(buf) {
+			buf = buf[:n]
+			break
+		}
+		buf = make([]byte, len(buf)*2)
+	}
+	stacks := make(map[int64]string)
+	for _, stack := range bytes.Split(buf, []byte("\n\n")) {
+		if id := parseGoroutineID(stack); id > 0 {
+			stacks[id] = string(stack)
+		}
+	}
+	return stacks
+}
+
+func currentGoroutineID() int64 {
+	var buf [64]byte
+	return parseGoroutineID(buf[:runtime.Stack(buf[:], false)])
+}
+
+// parseGoroutineID parse id from stack header "goroutine 18 [running]:".
+func parseGoroutineID(stack []byte) int64 {
+	stack = bytes.TrimPrefix(stack, []byte("goroutine "))
+	if i := bytes.IndexByte(stack, ' '); i > 0 {
+		id, _ := strconv.ParseInt(string(stack[:i]), 10, 64)
+		return id
+	}
+	return 0
+}
@@ -0,0 +1,82 @@
+package workerq
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestWorkerQueue_SetWatchdog(t *testing.T) {
+	wq := New(2).Start()
+	defer wq.Stop()
+
+	stuckC := make(chan StuckWorker, 1)
+	wq.SetWatchdog(time.Millisecond*50, true, func(s StuckWorker) {
+		stuckC <- s
+	})
+
+	release := make(chan struct{})
+	stuck := wq.AddWorkerFunc(nil, func(worker *Worker) error {
+		worker.Heartbeat()
+		<-release // deadlock until test release it
+		return nil
+	})
+	alive := wq.AddWorkerFunc(nil, func(worker *Worker) error {
+		for i := 0; i < 10; i++ {
+			worker.Heartbeat()
+			time.Sleep(time.Millisecond * 20)
+		}
+		return nil
+	})
+
+	select {
+	case s := <-stuckC:
+		if s.Worker != stuck {
+			t.Errorf("except stuck worker %v, actual %v", stuck.ID(), s.Worker.ID())
+		}
+		if !strings.Contains(s.Stack, "TestWorkerQueue_SetWatchdog") {
+			t.Errorf("except stack of stuck worker, actual %q", s.Stack)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("stuck worker not detected")
+	}
+
+	// canceled by watchdog
+	<-stuck.Done()
+	if n := len(wq.StuckWorkers()); n != 1 {
+		t.Errorf("except 1 stuck worker, actual %v", n)
+	}
+
+	alive.Wait()
+	close(release)
+}
+
+func TestWorkerQueue_SetWatchdogLater(t *testing.T) {
+	wq := New(1).Start()
+	defer wq.Stop()
+
+	release := make(chan struct{})
+	defer close(release)
+	started := make(chan struct{})
+	wq.AddWorkerFunc(nil, func(worker *Worker) error {
+		worker.Heartbeat()
+		close(started)
+		<-release
+		return nil
+	})
+	<-started
+
+	// worker started before watchdog enabled can be dumped too.
+	stuckC := make(chan StuckWorker, 1)
+	wq.SetWatchdog(time.Millisecond*50, false, func(s StuckWorker) {
+		stuckC <- s
+	})
+	select {
+	case s := <-stuckC:
+		if !strings.Contains(s.Stack, "TestWorkerQueue_SetWatchdogLater") {
+			t.Errorf("except stack of stuck worker, actual %q", s.Stack)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("stuck worker not detected")
+	}
+}
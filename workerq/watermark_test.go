@@ -0,0 +1,38 @@
+package workerq
+
+import (
+	"testing"
+)
+
+func TestWorkerQueue_SetBacklogWatermarks(t *testing.T) {
+	q := New(1)
+	defer q.Stop()
+	events := make(chan int, 4)
+	q.SetBacklogWatermarks(2, 0, func(high bool, n int) {
+		events <- n
+	})
+	ch, unsubscribe := q.SubscribeBacklogWatermarks()
+	defer unsubscribe()
+
+	var workers []*Worker
+	for i := 0; i < 3; i++ {
+		workers = append(workers, q.AddWorkerFunc(nil, nil))
+	}
+	if n := <-events; n != 2 {
+		t.Errorf("except high watermark crossed at 2, actual %d", n)
+	}
+	if v := <-ch; !v {
+		t.Error("except subscriber receives high")
+	}
+
+	q.Start()
+	for _, worker := range workers {
+		worker.Wait()
+	}
+	if n := <-events; n != 0 {
+		t.Errorf("except low watermark crossed at 0, actual %d", n)
+	}
+	if v := <-ch; v {
+		t.Error("except subscriber receives low")
+	}
+}
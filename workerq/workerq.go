@@ -3,40 +3,104 @@ package workerq
 import (
 	"context"
 	"fmt"
+	"log/slog"
+	"runtime/debug"
 	"sync"
+	"sync/atomic"
+	"time"
 
 	"go.uber.org/multierr"
 
+	"github.com/luweimy/goutil/internal/watermark"
+	"github.com/luweimy/goutil/observer"
+	"github.com/luweimy/goutil/qtrace"
 	"github.com/luweimy/goutil/syncq2"
 )
 
 type WorkerFunc func(worker *Worker) error
 
+// lastWorkerID is used to generate unique worker id.
+var lastWorkerID uint64
+
 type Worker struct {
+	id     uint64
+	name   string
+	base   context.Context // context passed to NewWorker
 	ctx    context.Context
 	cancel context.CancelFunc
 	begin  chan struct{} // use to notify worker process begin
 	work   WorkerFunc
 	errs   error
+
+	mu       sync.Mutex
+	result   interface{}
+	finished int32 // 1 if Do returned, otherwise worker is done by canceling
+
+	q        *WorkerQueue // the queue worker added to, nil if not added
+	progress progressHub  // progress subscribers of this worker
+
+	heartbeat int64 // unix nano of last heartbeat, 0 if never heartbeat
+	goid      int64 // id of goroutine processing the worker, use to dump stack
+
+	enqueuedAt time.Time   // time added to queue
+	startedAt  time.Time   // time processing started
+	slot       int         // executor slot + 1 recorded by timeline, 0 if not recorded
+	panicValue interface{} // recovered value if work panics
+	panicStack []byte
+
+	queuedSpan qtrace.Span     // nil if not traced
+	runSpan    qtrace.Span     // guarded by mu, nil if not traced
+	traceCtx   context.Context // guarded by mu, context carries runSpan
 }
 
 func NewWorker(ctx context.Context, work WorkerFunc) *Worker {
 	if ctx == nil {
 		ctx = context.Background()
 	}
+	base := ctx
 	ctx, cancel := context.WithCancel(ctx)
-	return &Worker{
+	c := &Worker{
+		id:     atomic.AddUint64(&lastWorkerID, 1),
+		base:   base,
 		ctx:    ctx,
 		cancel: cancel,
 		begin:  make(chan struct{}, 1),
 		work:   work,
 	}
+	// close progress subscribers however worker done, e.g. ctx of caller canceled while queued.
+	context.AfterFunc(ctx, c.progress.close)
+	return c
+}
+
+// ID return unique id of the worker.
+func (c *Worker) ID() uint64 {
+	return c.id
+}
+
+// SetName set name of the worker(e.g. job type or key), use to identify workers in diagnosis.
+// it should be called before the worker added to queue.
+func (c *Worker) SetName(name string) *Worker {
+	c.name = name
+	return c
+}
+
+func (c *Worker) Name() string {
+	return c.name
 }
 
 func (c *Worker) Begin() <-chan struct{} {
 	return c.begin
 }
 
+// Context return context of the worker, it is done when worker done or canceled.
+// if the queue is traced, it carries the running span once processing started.
+func (c *Worker) Context() context.Context {
+	if ctx := c.traceContext(); ctx != nil {
+		return ctx
+	}
+	return c.ctx
+}
+
 func (c *Worker) Done() <-chan struct{} {
 	return c.ctx.Done()
 }
@@ -46,14 +110,57 @@ func (c *Worker) Wait() error {
 	return c.Err()
 }
 
+// Err return errors of the worker, or error of its ctx if it is done before work returned.
 func (c *Worker) Err() error {
-	return c.errs
+	var errs error
+	withLock(&c.mu, func() {
+		if errs = c.errs; errs == nil && atomic.LoadInt32(&c.finished) == 0 {
+			errs = c.ctx.Err()
+		}
+	})
+	return errs
+}
+
+func (c *Worker) appendErr(err error) {
+	withLock(&c.mu, func() {
+		c.errs = multierr.Append(c.errs, err)
+	})
+}
+
+// SetResult set result of the worker, it is usually called in WorkerFunc.
+func (c *Worker) SetResult(result interface{}) {
+	withLock(&c.mu, func() {
+		c.result = result
+	})
+}
+
+// Result return result set by SetResult.
+func (c *Worker) Result() interface{} {
+	var result interface{}
+	withLock(&c.mu, func() {
+		result = c.result
+	})
+	return result
 }
 
 func (c *Worker) Do() {
+	c.do(nil)
+}
+
+// do process the worker, finish(if not nil) is called after work done and before notifying,
+// so the bookkeeping of queue is visible once Wait returns.
+func (c *Worker) do(finish func()) {
 	defer func() {
 		if err := recover(); err != nil {
-			c.errs = multierr.Append(c.errs, fmt.Errorf("panic: %v", err))
+			c.panicValue, c.panicStack = err, debug.Stack()
+			c.appendErr(fmt.Errorf("panic: %v", err))
+		}
+		withLock(&c.mu, func() {
+			atomic.StoreInt32(&c.finished, 1) // abort is no-op since now
+		})
+		c.progress.close() // no more progress after work done
+		if finish != nil {
+			finish()
 		}
 		c.cancel() // notify work done
 	}()
@@ -62,31 +169,67 @@ func (c *Worker) Do() {
 	if c.work != nil {
 		err := c.work(c)
 		if err != nil {
-			c.errs = multierr.Append(c.errs, err)
+			c.appendErr(err)
 		}
 	}
 }
 
 type WorkerQueue struct {
+	name   string
 	cancel context.CancelFunc
 	ctx    context.Context
 
 	backlog *syncq2.SyncQueue // backlog workers queue(unlimited size)
 	workers chan *Worker      // processing workers
 	mu      sync.RWMutex
+
+	// cap and len of workers, read without mu since SetConcurrency holds it until processing workers done.
+	concurrency int64
+	working     int64
+
+	progress progressHub // progress subscribers of all workers
+
+	queued    map[uint64]*Worker // backlog workers index by worker id
+	running   map[uint64]*Worker // processing workers index by worker id
+	runningMu sync.Mutex
+	watchdog  *watchdog
+	timeline  *timeline           // nil if timeline disabled
+	slo       *sloState           // nil if SLO not set
+	idle      watermark.Watermark // above if any worker queued or running
+	failures  []Failure           // ring of recent failures
+	failureAt int
+	resume    chan struct{} // not nil if paused, closed when resumed
+
+	hedge hedgeState
+
+	tracer qtrace.Tracer     // nil if not traced
+	obs    observer.Observer // nil if not observed
+	logger atomic.Pointer[slog.Logger]
 }
 
 // New create WorkerQueue object, max concurrency workers is allowed
 func New(concurrency int) *WorkerQueue {
+	return NewWithObserver(concurrency, nil)
+}
+
+// NewWithObserver create WorkerQueue object like New, events of backlog and workers are notified to obs.
+func NewWithObserver(concurrency int, obs observer.Observer) *WorkerQueue {
 	if concurrency <= 0 {
 		concurrency = 1
 	}
 	ctx, cancel := context.WithCancel(context.Background())
 	q := &WorkerQueue{
-		cancel:  cancel,
-		ctx:     ctx,
-		workers: make(chan *Worker, concurrency),
-		backlog: syncq2.New(),
+		cancel:      cancel,
+		ctx:         ctx,
+		workers:     make(chan *Worker, concurrency),
+		backlog:     syncq2.NewWithObserver(obs),
+		queued:      make(map[uint64]*Worker),
+		running:     make(map[uint64]*Worker),
+		concurrency: int64(concurrency),
+	}
+	q.idle.Set(1, 0, nil)
+	if observer.Enabled(obs) {
+		q.obs = obs
 	}
 	return q
 }
@@ -99,36 +242,83 @@ func (q *WorkerQueue) Start() *WorkerQueue {
 
 // Stop stop process backlog workers
 // stop can not stop processing workers and the workers not in backlog will be processed.
+// workers left in backlog are never processed, they are not counted by WaitIdle and Workers any more.
 func (q *WorkerQueue) Stop() {
 	q.cancel()
 	q.backlog.Destroy()
+	withLock(&q.runningMu, func() {
+		q.queued = make(map[uint64]*Worker)
+		q.idle.Update(len(q.running))
+	})
 }
 
 // SetConcurrency set concurrency will be blocked until processing workers all done.
 func (q *WorkerQueue) SetConcurrency(concurrency int) {
-	if cap(q.workers) == concurrency {
+	if q.Concurrency() == concurrency {
 		return
 	}
 	// resize workers channel, acquire wlock to block worker processing.
 	// the workers channel must be empty when can hold wlock.
+	start := time.Now()
 	withLock(&q.mu, func() {
-		if cap(q.workers) == concurrency {
+		old := cap(q.workers)
+		if old == concurrency {
 			return
 		}
 		q.workers = make(chan *Worker, concurrency)
+		atomic.StoreInt64(&q.concurrency, int64(concurrency))
+		q.log(slog.LevelInfo, "concurrency changed", slog.Int("from", old), slog.Int("to", concurrency),
+			slog.Duration("stall", time.Since(start)))
+		q.recordStall(start, old, concurrency)
 	})
 }
 
 // NumWorkingWorkers return num of working workers, top limit is concurrency.
+// it does not block while SetConcurrency waiting.
 func (q *WorkerQueue) NumWorkingWorkers() int {
-	var n = 0
-	withLock(q.mu.RLocker(), func() {
-		n = len(q.workers)
-	})
-	return n
+	return int(atomic.LoadInt64(&q.working))
+}
+
+// Concurrency return max num of working workers, it is the old one while SetConcurrency waiting.
+func (q *WorkerQueue) Concurrency() int {
+	return int(atomic.LoadInt64(&q.concurrency))
+}
+
+// NumBacklogWorkers return num of workers waiting in backlog.
+func (q *WorkerQueue) NumBacklogWorkers() int {
+	return q.backlog.Len()
+}
+
+// SetBacklogWatermarks notify fn(true, n) when num of backlog workers rises to high,
+// and fn(false, n) when it falls back to low afterwards, so producers can pause and resume intake proactively.
+// fn may be nil, it is called synchronously and must not block or call the queue. high <= 0 disable notifications.
+func (q *WorkerQueue) SetBacklogWatermarks(high, low int, fn func(high bool, n int)) *WorkerQueue {
+	q.backlog.SetWatermarks(high, low, fn)
+	return q
+}
+
+// SubscribeBacklogWatermarks return channel receives true when num of backlog workers rises to high watermark,
+// and false when it falls back to low. slow subscriber only receives the latest state,
+// the channel will be closed when unsubscribe func called.
+func (q *WorkerQueue) SubscribeBacklogWatermarks() (<-chan bool, func()) {
+	return q.backlog.SubscribeWatermarks()
 }
 
 func (q *WorkerQueue) AddWorker(worker *Worker) <-chan struct{} {
+	worker.q = q
+	worker.enqueuedAt = time.Now()
+	q.traceQueued(worker)
+	withLock(&q.runningMu, func() {
+		if q.ctx.Err() != nil {
+			return // stopped, never processed
+		}
+		q.queued[worker.id] = worker
+		q.idle.Update(len(q.queued) + len(q.running))
+	})
+	// drop worker once its ctx done while queued, the dispatcher skips it.
+	context.AfterFunc(worker.ctx, func() {
+		q.dropQueued(worker)
+	})
 	q.backlog.Enqueue(worker)
 	return worker.Done()
 }
@@ -143,6 +333,9 @@ func (q *WorkerQueue) dispatchWorkers() {
 	for {
 		select {
 		case worker := <-q.backlog.DequeueC():
+			if !q.waitResumed() {
+				return
+			}
 			q.dispatchWorker(worker.(*Worker))
 		case <-q.ctx.Done():
 			// Stop is called.
@@ -152,18 +345,134 @@ func (q *WorkerQueue) dispatchWorkers() {
 }
 
 func (q *WorkerQueue) dispatchWorker(worker *Worker) {
+	if worker.ctx.Err() != nil {
+		// canceled while queued, never process it.
+		q.dropQueued(worker)
+		return
+	}
 	// acquire rlock during work processing to block resize workers channel buffer.
 	q.mu.RLock()
-	// worker must be processed, even through ctx canceled.
+	// worker must be processed, even through queue ctx canceled.
 	q.workers <- worker
+	atomic.AddInt64(&q.working, 1)
 	// avoid work processing block dispatch goroutine.
-	go func() {
-		defer func() { // in case worker do panics
+	go q.doLabeled(worker, func() {
+		defer func() {
+			atomic.AddInt64(&q.working, -1)
 			<-q.workers
 			q.mu.RUnlock()
 		}()
-		worker.Do()
-	}()
+		if !q.addRunning(worker) {
+			return // canceled while waiting for a slot
+		}
+		start := q.workerStart(worker)
+		defer q.removeRunning(worker) // in case worker do panics
+		worker.do(func() {
+			q.workerFinish(worker, start)
+		})
+	})
+}
+
+func (q *WorkerQueue) workerStart(worker *Worker) time.Time {
+	now := time.Now()
+	q.traceStart(worker)
+	q.logWorkerStart(worker, now.Sub(worker.enqueuedAt))
+	if q.obs != nil {
+		q.obs.OnWorkerStart(observer.WorkerEvent{
+			Time:     now,
+			WorkerID: worker.id,
+			Wait:     now.Sub(worker.enqueuedAt),
+		})
+	}
+	return now
+}
+
+func (q *WorkerQueue) workerFinish(worker *Worker, start time.Time) {
+	now := time.Now()
+	err := worker.Err()
+	if err != nil {
+		q.addFailure(worker, err, now.Sub(start))
+	}
+	q.traceFinish(worker, err)
+	q.recordTimeline(worker, start, now, err)
+	q.logWorkerFinish(worker, now.Sub(start), err)
+	if q.obs == nil {
+		return
+	}
+	e := observer.WorkerEvent{
+		Time:     now,
+		WorkerID: worker.id,
+		Wait:     start.Sub(worker.enqueuedAt),
+		Run:      now.Sub(start),
+		Err:      err,
+	}
+	if worker.panicValue != nil {
+		p := e
+		p.Panic, p.Stack = worker.panicValue, worker.panicStack
+		q.obs.OnWorkerPanic(p)
+	}
+	q.obs.OnWorkerFinish(e)
+}
+
+// addRunning move worker from queued to running, return false if worker canceled while queued.
+func (q *WorkerQueue) addRunning(worker *Worker) bool {
+	if worker.ctx.Err() != nil {
+		q.dropQueued(worker)
+		return false
+	}
+	// record goid even if watchdog disabled, so workers started before it enabled can be dumped too.
+	atomic.StoreInt64(&worker.goid, currentGoroutineID())
+	withLock(&q.runningMu, func() {
+		worker.startedAt = time.Now()
+		q.acquireSlot(worker)
+		q.observeWait(worker.startedAt.Sub(worker.enqueuedAt), worker.startedAt)
+		delete(q.queued, worker.id)
+		q.running[worker.id] = worker
+		q.idle.Update(len(q.queued) + len(q.running))
+	})
+	return true
+}
+
+// dropQueued remove worker canceled while queued, it is no-op if worker is not queued.
+// ctx error is recorded unless abort recorded its error, so Wait and Err agree with WaitAll.
+func (q *WorkerQueue) dropQueued(worker *Worker) {
+	dropped := false
+	withLock(&q.runningMu, func() {
+		if _, dropped = q.queued[worker.id]; dropped {
+			delete(q.queued, worker.id)
+			q.idle.Update(len(q.queued) + len(q.running))
+		}
+	})
+	if !dropped {
+		return
+	}
+	withLock(&worker.mu, func() {
+		if worker.errs == nil {
+			worker.errs = worker.ctx.Err()
+		}
+	})
+	if worker.queuedSpan != nil {
+		worker.queuedSpan.End()
+	}
+}
+
+func (q *WorkerQueue) removeRunning(worker *Worker) {
+	withLock(&q.runningMu, func() {
+		delete(q.running, worker.id)
+		q.idle.Update(len(q.queued) + len(q.running))
+	})
+}
+
+// runningWorkers return snapshot of processing workers.
+func (q *WorkerQueue) runningWorkers() []*Worker {
+	var workers []*Worker
+	withLock(&q.runningMu, func() {
+		workers = make([]*Worker, 0, len(q.running))
+		for _, worker := range q.running {
+			workers = append(workers, worker)
+		}
+	})
+	return workers
 }
 
 func withLock(lk sync.Locker, fn func()) {